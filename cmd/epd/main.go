@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+// go run ./cmd/epd -movetime 1s wac.epd
+
+func main() {
+	depth := flag.Int("depth", 0, "search depth per position, 0 for no limit")
+	moveTime := flag.Duration("movetime", time.Second, "search time per position, 0 for no limit")
+	hashMB := flag.Int("hash", 64, "transposition table size in MB")
+	verbose := flag.Bool("v", false, "print passing positions too")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: epd [-depth n] [-movetime d] [-hash mb] file.epd...")
+		os.Exit(2)
+	}
+	if *depth == 0 && *moveTime == 0 {
+		fmt.Fprintln(os.Stderr, "epd: set -depth or -movetime, otherwise every search runs to the depth limit")
+		os.Exit(2)
+	}
+
+	limits := chess.SearchLimits{Depth: *depth, MoveTime: *moveTime}
+	var total suiteResult
+	for _, path := range flag.Args() {
+		r, err := runFile(path, limits, *hashMB, *verbose)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "epd:", err)
+			os.Exit(1)
+		}
+		r.print(path)
+		total.add(r)
+	}
+	if flag.NArg() > 1 {
+		total.print("total")
+	}
+}
+
+type suiteResult struct {
+	passed, failed, skipped int
+	nodes                   uint64
+	elapsed                 time.Duration
+}
+
+func (r *suiteResult) add(o suiteResult) {
+	r.passed += o.passed
+	r.failed += o.failed
+	r.skipped += o.skipped
+	r.nodes += o.nodes
+	r.elapsed += o.elapsed
+}
+
+func (r suiteResult) print(name string) {
+	nps := 0.0
+	if r.elapsed > 0 {
+		nps = float64(r.nodes) / r.elapsed.Seconds()
+	}
+	fmt.Printf("%s: %d/%d passed, %d skipped, %d nodes in %s, %.0f nps\n",
+		name, r.passed, r.passed+r.failed, r.skipped, r.nodes, r.elapsed.Round(time.Millisecond), nps)
+}
+
+func runFile(path string, limits chess.SearchLimits, hashMB int, verbose bool) (suiteResult, error) {
+	var result suiteResult
+	f, err := os.Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	searcher := chess.NewSearcher(hashMB)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := fmt.Sprintf("%s:%d", path, lineNo)
+
+		epd, err := chess.ParseEPD(line)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+		if id := epd.ID(); id != "" {
+			name = id
+		}
+		best, err := epd.Moves("bm")
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+		avoid, err := epd.Moves("am")
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(best) == 0 && len(avoid) == 0 {
+			result.skipped++
+			continue
+		}
+
+		board := epd.Board
+		start := time.Now()
+		found := searcher.Search(&board, []chess.Board{board}, limits)
+		elapsed := time.Since(start)
+		result.nodes += found.Nodes
+		result.elapsed += elapsed
+
+		pass := (len(best) == 0 || contains(best, found.Move)) && !contains(avoid, found.Move)
+		if pass {
+			result.passed++
+		} else {
+			result.failed++
+		}
+		if !pass || verbose {
+			status := "FAIL"
+			if pass {
+				status = "ok  "
+			}
+			fmt.Printf("%s %-20s played %-7s bm %-12s am %-12s depth %2d score %6d nodes %d\n",
+				status, name, chess.MoveToSAN(&board, found.Move),
+				strings.Join(epd.Ops["bm"], " "), strings.Join(epd.Ops["am"], " "),
+				found.Depth, found.Score, found.Nodes)
+		}
+	}
+	return result, scanner.Err()
+}
+
+func contains(moves []chess.Move, m chess.Move) bool {
+	for _, candidate := range moves {
+		if candidate == m {
+			return true
+		}
+	}
+	return false
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/zefir/szaszki-go-backend/config"
 	authclient "github.com/zefir/szaszki-go-backend/grpc"
 	"github.com/zefir/szaszki-go-backend/internal"
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
 	"github.com/zefir/szaszki-go-backend/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -48,6 +49,17 @@ func main() {
 
 	logger.Log.Info().Str("status", "booted").Msg("Szaszki server starting up")
 
+	if config.AppConfig.ENGINE_TRACE {
+		chess.SetTracer(internal.EngineTracer{})
+		logger.Log.Info().Msg("Chess engine tracing enabled")
+	}
+
+	if path := config.AppConfig.OPENING_BOOK; path != "" {
+		if err := internal.LoadOpeningBook(path); err != nil {
+			logger.Log.Warn().Err(err).Str("path", path).Msg("Can't load opening book")
+		}
+	}
+
 	go logRuntimeStats()
 	authclient.Init(conn)
 
@@ -4,13 +4,18 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	WS_PORT   string
-	GRPC_PORT string
+	WS_PORT         string
+	GRPC_PORT       string
+	ENGINE_TRACE    bool
+	UCI_ENGINE_PATH string        // external engine for vs computer games, empty uses the built-in bot
+	OPENING_BOOK    string        // polyglot .bin book for the built-in bot, optional
+	RECONNECT_GRACE time.Duration // how long a game waits for a player whose connections all dropped
 }
 
 var AppConfig Config
@@ -35,7 +40,25 @@ func Load() {
 	}
 
 	AppConfig = Config{
-		WS_PORT:   os.Getenv("WS_PORT"),
-		GRPC_PORT: os.Getenv("GRPC_PORT"),
+		WS_PORT:         os.Getenv("WS_PORT"),
+		GRPC_PORT:       os.Getenv("GRPC_PORT"),
+		ENGINE_TRACE:    os.Getenv("ENGINE_TRACE") == "true",
+		UCI_ENGINE_PATH: os.Getenv("UCI_ENGINE_PATH"),
+		OPENING_BOOK:    os.Getenv("OPENING_BOOK"),
+		RECONNECT_GRACE: durationEnv("RECONNECT_GRACE", time.Minute),
 	}
 }
+
+// durationEnv reads a duration like "30s", falling back to def when unset or invalid
+func durationEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Println("Invalid", name, value, "using", def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zefir/szaszki-go-backend/config"
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+	"github.com/zefir/szaszki-go-backend/internal/uci"
+	"github.com/zefir/szaszki-go-backend/logger"
+)
+
+// search limits per bot strength, level 1 is the weakest
+var botLevels = []chess.SearchLimits{
+	{Depth: 1},
+	{Depth: 2},
+	{Depth: 3, MoveTime: 500 * time.Millisecond},
+	{Depth: 5, MoveTime: time.Second},
+	{Depth: 8, MoveTime: 2 * time.Second},
+	{MoveTime: 3 * time.Second},
+}
+
+const botTableSizeMB = 16
+
+// openingBook gives the built-in bot some variety in the opening, nil when none is configured
+var openingBook *chess.PolyglotBook
+
+func LoadOpeningBook(path string) error {
+	book, err := chess.LoadPolyglotBook(path)
+	if err != nil {
+		return err
+	}
+	openingBook = book
+	logger.Log.Info().Int("entries", book.Len()).Msg("Opening book loaded")
+	return nil
+}
+
+// BotEngine picks the next move from the game's positions (start first, current last)
+// and the moves played between them
+type BotEngine interface {
+	ChooseMove(history []chess.Board, moves []chess.Move) (chess.Move, error)
+}
+
+type Bot struct {
+	Level  int
+	Engine BotEngine // swapped for the external engine once it's up, read it through engine()
+
+	mu       sync.Mutex    // one search at a time, engines and searchers aren't safe for concurrent use
+	engineMu sync.Mutex    // held to swap Engine or to close, so an engine that starts late is never left running
+	fallback *searchEngine // made on the first engine failure, then reused
+	closed   atomic.Bool
+	rejected atomic.Bool // the engine played an illegal move, the built-in search takes over
+}
+
+// searchEngine is the built-in alpha-beta search
+type searchEngine struct {
+	limits   chess.SearchLimits
+	searcher *chess.Searcher
+}
+
+func newSearchEngine(limits chess.SearchLimits) *searchEngine {
+	return &searchEngine{limits: limits, searcher: chess.NewSearcher(botTableSizeMB)}
+}
+
+func (s *searchEngine) ChooseMove(history []chess.Board, moves []chess.Move) (chess.Move, error) {
+	board := history[len(history)-1]
+	if openingBook != nil {
+		if m, ok := openingBook.WeightedMove(&board); ok {
+			return m, nil
+		}
+	}
+	result := s.searcher.Search(&board, history, s.limits)
+	logger.Log.Debug().Int("depth", result.Depth).Int("score", result.Score).Uint64("nodes", result.Nodes).Msg("bot move")
+	return result.Move, nil
+}
+
+// NewBotClient makes a pseudo-client for the computer opponent, it has no connections
+// and answers through the game's MoveChannel. An external UCI engine is used when configured,
+// the built-in search plays until it has started.
+func NewBotClient(level int) *Client {
+	if level < 1 {
+		level = 1
+	}
+	if level > len(botLevels) {
+		level = len(botLevels)
+	}
+	limits := botLevels[level-1]
+
+	bot := &Bot{Level: level, Engine: newSearchEngine(limits)}
+	if path := config.AppConfig.UCI_ENGINE_PATH; path != "" {
+		// the handshake may take a while, the game doesn't wait for it
+		go bot.startEngine(path, uci.GoParams{Depth: limits.Depth, MoveTime: limits.MoveTime})
+	}
+
+	return &Client{
+		Conns:         make(map[uint64]net.Conn),
+		QueuedInModes: make(map[uint16]bool),
+		Bot:           bot,
+	}
+}
+
+// startEngine launches the external engine and hands it the next searches, a bot closed meanwhile shuts it down again
+func (b *Bot) startEngine(path string, limits uci.GoParams) {
+	e, err := uci.Start(path)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("path", path).Msg("Can't start uci engine, using the built-in bot")
+		return
+	}
+
+	b.engineMu.Lock()
+	defer b.engineMu.Unlock()
+	if b.closed.Load() {
+		if err := e.Close(); err != nil {
+			logger.Log.Warn().Err(err).Msg("Closing bot engine")
+		}
+		return
+	}
+	b.Engine = uci.NewPlayer(e, limits)
+}
+
+func (b *Bot) engine() BotEngine {
+	b.engineMu.Lock()
+	defer b.engineMu.Unlock()
+	return b.Engine
+}
+
+// ChooseMove never fails, if the engine does the built-in search plays a quick move instead
+func (b *Bot) ChooseMove(history []chess.Board, moves []chess.Move) chess.Move {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	engine := b.engine()
+	if !b.rejected.Load() {
+		m, err := engine.ChooseMove(history, moves)
+		if err == nil || b.closed.Load() {
+			// a closed bot's game is over, nobody waits for the move
+			return m
+		}
+		logger.Log.Warn().Err(err).Int("level", b.Level).Msg("Bot engine failed, falling back to the built-in search")
+	}
+	if b.fallback == nil {
+		if builtin, ok := engine.(*searchEngine); ok {
+			b.fallback = builtin
+		} else {
+			b.fallback = newSearchEngine(botLevels[0])
+		}
+	}
+	m, _ := b.fallback.ChooseMove(history, moves)
+	return m
+}
+
+// Reject hands the rest of the game to the built-in search, the engine's move wasn't legal
+func (b *Bot) Reject() {
+	b.rejected.Store(true)
+}
+
+// Close shuts down engines that run in their own process
+func (b *Bot) Close() {
+	b.engineMu.Lock()
+	b.closed.Store(true)
+	engine := b.Engine
+	b.engineMu.Unlock()
+
+	if closer, ok := engine.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Log.Warn().Err(err).Msg("Closing bot engine")
+		}
+	}
+}
+
+// botStarts holds the users whose computer game is being set up, so parallel requests can't slip past the check
+var botStarts sync.Map
+
+// StartComputerGame pairs a player with a bot of the given level, colors are drawn at random.
+// Each bot holds a transposition table or an engine process, so a player gets one computer game at a time.
+func StartComputerGame(client *Client, level int) *GameSession {
+	if _, busy := botStarts.LoadOrStore(client.UserID, true); busy {
+		sendActionRejected(client, 0, ClientCmds.SearchingForGame, ActionRejectAlreadyPlaying)
+		return nil
+	}
+	defer botStarts.Delete(client.UserID)
+
+	for _, game := range GetGameKeeper().GamesOf(client.UserID) {
+		if GameMode(game.Mode) == ModeVsComputer {
+			logger.Log.Warn().Uint32("clientId", client.UserID).Uint32("gameId", game.ID).Msg("Client already plays the computer")
+			sendActionRejected(client, game.ID, ClientCmds.SearchingForGame, ActionRejectAlreadyPlaying)
+			return nil
+		}
+	}
+
+	bot := NewBotClient(level)
+	players := []*Client{client, bot}
+	if rand.Intn(2) == 0 {
+		players[0], players[1] = bot, client
+	}
+	logger.Log.Info().Uint32("clientId", client.UserID).Int("level", bot.Bot.Level).Msg("Starting game against the computer")
+	return GetGameKeeper().CreateGame(players, uint16(ModeVsComputer))
+}
+
+// requestBotMove lets the bot think in the background when it's on move,
+// the answer arrives through MoveChannel like any other move, tagged with the request it answers
+func (g *GameSession) requestBotMove() {
+	player := g.PlayerOf(g.Board.ActiveColor())
+	if player.Bot == nil {
+		return
+	}
+
+	g.botRequest++
+	request := g.botRequest
+	history := append([]chess.Board(nil), g.BoardHistory...)
+	moves := append([]chess.Move(nil), g.MoveHistory...)
+	go func() {
+		m := player.Bot.ChooseMove(history, moves)
+		select {
+		case g.MoveChannel <- PlayerMove{From: m.From, To: m.To, PromoteTo: m.Promotion, Player: player, BotRequest: request}:
+		case <-g.Done():
+		}
+	}()
+}
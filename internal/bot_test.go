@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zefir/szaszki-go-backend/config"
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+func TestBotDoesntWaitForEngine(t *testing.T) {
+	// an engine that never finishes its handshake
+	path := filepath.Join(t.TempDir(), "engine.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nsleep 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config.AppConfig.UCI_ENGINE_PATH = path
+	t.Cleanup(func() { config.AppConfig.UCI_ENGINE_PATH = "" })
+
+	started := time.Now()
+	bot := NewBotClient(1).Bot
+	defer bot.Close()
+	if waited := time.Since(started); waited > time.Second {
+		t.Fatalf("NewBotClient took %v", waited)
+	}
+
+	start := chess.NewStartingPosition()
+	m := bot.ChooseMove([]chess.Board{start}, nil)
+	if !chess.IsMoveLegal(&start, m.From, m.To, m.Promotion) {
+		t.Fatalf("built-in search played %v", m)
+	}
+}
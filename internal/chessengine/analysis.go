@@ -0,0 +1,129 @@
+package chess
+
+import "math/bits"
+
+// Pin is a piece that can't leave the line between its king and an enemy slider
+type Pin struct {
+	Square int8
+	Pinner int8
+	Ray    Bitboard // squares between the king and the pinner, pinner included; the pinned piece may only move here
+}
+
+// Analysis is a static snapshot of the position, nothing is searched
+type Analysis struct {
+	Checkers Bitboard     // enemy pieces giving check to the side to move
+	Pins     [2][]Pin     // [color] pieces pinned to that color's king
+	Attacks  [2][64]uint8 // [color][square] number of that color's pieces attacking the square
+	Material [2]int       // centipawns per color, pockets included
+	Hanging  [2]Bitboard  // [color] pieces attacked by the other side and not defended
+}
+
+func Analyze(board *Board) Analysis {
+	a := Analysis{
+		Checkers: Checkers(board),
+		Attacks:  AttackCounts(board),
+		Material: Material(board),
+	}
+	for color := int8(0); color < 2; color++ {
+		a.Pins[color] = Pins(board, color)
+		a.Hanging[color] = HangingPieces(board, color)
+	}
+	return a
+}
+
+// AttackersOf returns color's pieces that attack sq, whatever stands on it
+func AttackersOf(board *Board, sq int, color int8) Bitboard {
+	mask := Bitboard(1) << sq
+	occupied := board.Occupied[White] | board.Occupied[Black]
+
+	attackers := knightMoves[sq] & board.Knights[color]
+	attackers |= kingMoves[sq] & board.Kings[color]
+	attackers |= PawnAttacks(mask, board.Pawns[color], color == Black)
+	attackers |= bishopAttacks(sq, occupied) & (board.Bishops[color] | board.Queens[color])
+	attackers |= rookAttacks(sq, occupied) & (board.Rooks[color] | board.Queens[color])
+	return attackers
+}
+
+// Checkers returns the pieces checking the side to move
+func Checkers(board *Board) Bitboard {
+	color := board.ActiveColor()
+	if board.Kings[color] == 0 {
+		return 0
+	}
+	kingSq := bits.TrailingZeros64(uint64(board.Kings[color]))
+	return AttackersOf(board, kingSq, 1-color)
+}
+
+// Pins lists color's pieces pinned against color's king
+func Pins(board *Board, color int8) []Pin {
+	var pins []Pin
+	visitPins(board, color, func(p Pin) { pins = append(pins, p) })
+	return pins
+}
+
+func visitPins(board *Board, color int8, visit func(Pin)) {
+	if board.Kings[color] == 0 {
+		return
+	}
+	kingSq := bits.TrailingZeros64(uint64(board.Kings[color]))
+	enemy := 1 - color
+	occupied := board.Occupied[White] | board.Occupied[Black]
+
+	// sliders that would see the king if our own pieces weren't there
+	diagonal := bishopAttacks(kingSq, board.Occupied[enemy]) & (board.Bishops[enemy] | board.Queens[enemy])
+	straight := rookAttacks(kingSq, board.Occupied[enemy]) & (board.Rooks[enemy] | board.Queens[enemy])
+
+	for snipers := diagonal | straight; snipers != 0; {
+		sniper := PopLSB(&snipers)
+		between := Between(kingSq, sniper)
+		blockers := between & occupied
+		if CountBits(blockers) == 1 && blockers&board.Occupied[color] != 0 {
+			visit(Pin{
+				Square: int8(bits.TrailingZeros64(uint64(blockers))),
+				Pinner: int8(sniper),
+				Ray:    between | Bitboard(1)<<sniper,
+			})
+		}
+	}
+}
+
+// AttackCounts counts the attackers of every square for both colors
+func AttackCounts(board *Board) [2][64]uint8 {
+	var counts [2][64]uint8
+	for color := int8(0); color < 2; color++ {
+		for sq := 0; sq < 64; sq++ {
+			counts[color][sq] = uint8(CountBits(AttackersOf(board, sq, color)))
+		}
+	}
+	return counts
+}
+
+// Material sums piece values per color, pieces in hand count like pieces on the board
+func Material(board *Board) [2]int {
+	var material [2]int
+	for color := int8(0); color < 2; color++ {
+		for piece := Pawn; piece <= Queen; piece++ {
+			count := CountBits(*board.pieceBB(color, piece)) + int(board.Pockets[color][piece])
+			material[color] += count * pieceValues[piece]
+		}
+	}
+	return material
+}
+
+// MaterialBalance is white's material minus black's, in centipawns
+func MaterialBalance(board *Board) int {
+	m := Material(board)
+	return m[White] - m[Black]
+}
+
+// HangingPieces returns color's pieces, king excluded, that the other side attacks and color doesn't defend
+func HangingPieces(board *Board, color int8) Bitboard {
+	var hanging Bitboard
+	for pieces := board.Occupied[color] &^ board.Kings[color]; pieces != 0; {
+		sq := PopLSB(&pieces)
+		if AttackersOf(board, sq, 1-color) != 0 && AttackersOf(board, sq, color) == 0 {
+			hanging |= Bitboard(1) << sq
+		}
+	}
+	return hanging
+}
@@ -0,0 +1,122 @@
+package chess
+
+import "testing"
+
+func sqBB(squares ...string) Bitboard {
+	var bb Bitboard
+	for _, s := range squares {
+		bb |= Bitboard(1) << mustSquare(s)
+	}
+	return bb
+}
+
+func mustSquare(s string) int8 {
+	sq, err := ParseSquare(s)
+	if err != nil {
+		panic(err)
+	}
+	return sq
+}
+
+func TestAttackersOf(t *testing.T) {
+	board := mustParseFEN(t, "4k3/8/8/3q4/2P1N3/1B6/8/3RK3 w - - 0 1")
+	// the b3 bishop is blocked by its own pawn and the knight doesn't reach d5
+	if got, want := AttackersOf(&board, int(mustSquare("d5")), White), sqBB("c4", "d1"); got != want {
+		t.Errorf("white attackers of d5: got %x, want %x", got, want)
+	}
+	if got, want := AttackersOf(&board, int(mustSquare("e4")), Black), sqBB("d5"); got != want {
+		t.Errorf("black attackers of e4: got %x, want %x", got, want)
+	}
+}
+
+func TestCheckers(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want Bitboard
+	}{
+		{StartingFEN, 0},
+		{"4k3/8/8/8/8/8/8/4RK2 b - - 0 1", sqBB("e1")},
+		{"4k3/8/3N4/8/8/8/8/4RK2 b - - 0 1", sqBB("e1", "d6")}, // double check
+		{"4k3/4P3/8/8/8/8/8/5K2 b - - 0 1", 0},
+		{"4k3/5P2/8/8/8/8/8/5K2 b - - 0 1", sqBB("f7")},
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		if got := Checkers(&board); got != tt.want {
+			t.Errorf("%s: got %x, want %x", tt.fen, got, tt.want)
+		}
+	}
+}
+
+func TestPins(t *testing.T) {
+	tests := []struct {
+		fen   string
+		color int8
+		want  []Pin
+	}{
+		{StartingFEN, White, nil},
+		{"4r2k/8/8/8/8/8/4B3/4K3 w - - 0 1", White, []Pin{{Square: mustSquare("e2"), Pinner: mustSquare("e8"), Ray: sqBB("e2", "e3", "e4", "e5", "e6", "e7", "e8")}}},
+		{"7k/8/8/b7/8/2N5/8/4K3 w - - 0 1", White, []Pin{{Square: mustSquare("c3"), Pinner: mustSquare("a5"), Ray: sqBB("d2", "c3", "b4", "a5")}}},
+		{"4k3/4n3/8/8/8/8/8/4RK2 b - - 0 1", Black, []Pin{{Square: mustSquare("e7"), Pinner: mustSquare("e1"), Ray: sqBB("e1", "e2", "e3", "e4", "e5", "e6", "e7")}}},
+		{"4r2k/8/8/8/4P3/8/4B3/4K3 w - - 0 1", White, nil}, // two blockers
+		{"7k/8/8/b7/1p6/2N5/8/4K3 w - - 0 1", White, nil},  // enemy pawn in the way
+		{"7k/8/8/n7/8/2N5/8/4K3 w - - 0 1", White, nil},    // knights don't pin
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		got := Pins(&board, tt.color)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.fen, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %+v, want %+v", tt.fen, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestAttackCounts(t *testing.T) {
+	board := NewStartingPosition()
+	counts := AttackCounts(&board)
+	// f3 is covered by the e2 and g2 pawns and the g1 knight
+	if got := counts[White][mustSquare("f3")]; got != 3 {
+		t.Errorf("white attackers of f3: got %d, want 3", got)
+	}
+	if got := counts[Black][mustSquare("f3")]; got != 0 {
+		t.Errorf("black attackers of f3: got %d, want 0", got)
+	}
+	// e2 pawn is defended by the queen, king, bishop and knight
+	if got := counts[White][mustSquare("e2")]; got != 4 {
+		t.Errorf("white defenders of e2: got %d, want 4", got)
+	}
+}
+
+func TestMaterialAndHanging(t *testing.T) {
+	board := mustParseFEN(t, "4k3/8/8/3n4/8/8/1B6/R3K3 w - - 0 1")
+	if got := Material(&board); got != [2]int{Black: 320, White: 830} {
+		t.Errorf("material: got %v", got)
+	}
+	if got := MaterialBalance(&board); got != 510 {
+		t.Errorf("balance: got %d, want 510", got)
+	}
+
+	// the b2 bishop is undefended but nothing attacks it
+	if got := HangingPieces(&board, White); got != 0 {
+		t.Errorf("white hanging: got %x", got)
+	}
+
+	board = mustParseFEN(t, "4k3/8/8/3n4/8/5B2/8/R3K3 w - - 0 1")
+	if got := HangingPieces(&board, Black); got != sqBB("d5") {
+		t.Errorf("black hanging: got %x, want d5", got)
+	}
+	if got := HangingPieces(&board, White); got != 0 {
+		t.Errorf("white hanging: got %x", got)
+	}
+
+	a := Analyze(&board)
+	if a.Hanging[Black] != sqBB("d5") || a.Material[White] != 830 || a.Checkers != 0 {
+		t.Errorf("Analyze: got %+v", a)
+	}
+}
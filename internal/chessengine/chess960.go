@@ -0,0 +1,68 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+//https://www.chessprogramming.org/Chess960_Numbering_Scheme
+
+const StandardChess960Index = 518
+
+// knight placements on the five squares left after bishops and queen, indexed by Scharnagl digit
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960BackRank returns the white back rank for Scharnagl position n (0-959) as FEN letters
+func Chess960BackRank(n int) (string, error) {
+	if n < 0 || n >= 960 {
+		return "", fmt.Errorf("chess960: position %d out of range", n)
+	}
+
+	var rank [8]byte
+	rank[(n%4)*2+1] = 'B' // light-squared bishop on b, d, f or h
+	n /= 4
+	rank[(n%4)*2] = 'B' // dark-squared bishop on a, c, e or g
+	n /= 4
+
+	// the rest fill the remaining empty squares from the a-file onwards
+	place := func(skip int, piece byte) {
+		for file := range rank {
+			if rank[file] != 0 {
+				continue
+			}
+			if skip == 0 {
+				rank[file] = piece
+				return
+			}
+			skip--
+		}
+	}
+	place(n%6, 'Q')
+	n /= 6
+	knights := chess960Knights[n]
+	place(knights[1], 'N')
+	place(knights[0], 'N')
+	place(0, 'R')
+	place(0, 'K')
+	place(0, 'R')
+	return string(rank[:]), nil
+}
+
+// NewChess960Position sets up Scharnagl position n with full castling rights,
+// 518 is the standard starting position
+func NewChess960Position(n int) (Board, error) {
+	backRank, err := Chess960BackRank(n)
+	if err != nil {
+		return Board{}, err
+	}
+	fen := strings.ToLower(backRank) + "/pppppppp/8/8/8/8/PPPPPPPP/" + backRank + " w KQkq - 0 1"
+	board, err := ParseFEN(fen)
+	if err != nil {
+		return board, err
+	}
+	board.Flags |= Chess960
+	return board, nil
+}
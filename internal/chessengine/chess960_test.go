@@ -0,0 +1,135 @@
+package chess
+
+import "testing"
+
+var chess960PerftCases = []struct {
+	fen   string
+	nodes []uint64 // counts for depth 1, 2, ...
+}{
+	{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189, 326672}},
+	{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002, 667366}},
+	{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []uint64{20, 479, 10471, 273318}},
+	{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []uint64{22, 593, 13440, 382958}},
+	{"1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []uint64{28, 1120, 31058, 1171749}},
+}
+
+func TestChess960Perft(t *testing.T) {
+	for _, tc := range chess960PerftCases {
+		board := mustParseFEN(t, tc.fen)
+		if board.Flags&Chess960 == 0 {
+			t.Fatalf("%s: not flagged as Chess960", tc.fen)
+		}
+		for i, want := range tc.nodes {
+			depth := i + 1
+			if depth == 4 && testing.Short() {
+				break
+			}
+			if got := Perft(&board, depth); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tc.fen, depth, got, want)
+			}
+		}
+		checkHashes(t, &board, 3)
+		checkUnmake(t, &board, 3)
+	}
+}
+
+func TestChess960StandardIndex(t *testing.T) {
+	board, err := NewChess960Position(StandardChess960Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := board.FEN(); got != StartingFEN {
+		t.Errorf("position 518 = %s", got)
+	}
+	if board.Flags&Chess960 == 0 {
+		t.Error("position 518 should still castle by Chess960 rules")
+	}
+}
+
+func TestChess960BackRanks(t *testing.T) {
+	known := map[int]string{
+		0:   "BBQNNRKR",
+		518: "RNBQKBNR",
+		959: "RKRNNQBB",
+	}
+	for n, want := range known {
+		if got, _ := Chess960BackRank(n); got != want {
+			t.Errorf("position %d = %s, want %s", n, got, want)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for n := 0; n < 960; n++ {
+		backRank, err := Chess960BackRank(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[backRank] {
+			t.Fatalf("position %d repeats %s", n, backRank)
+		}
+		seen[backRank] = true
+
+		board, err := NewChess960Position(n)
+		if err != nil {
+			t.Fatalf("position %d: %v", n, err)
+		}
+		if moves := len(GenerateLegalMoves(&board)); moves < 18 {
+			t.Errorf("position %d (%s) has only %d moves", n, backRank, moves)
+		}
+	}
+
+	if _, err := Chess960BackRank(960); err == nil {
+		t.Error("expected an error for position 960")
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	// king on b1 with rooks on a1 and h1: O-O-O leaves the king on c1, the move is king takes rook
+	board := mustParseFEN(t, "r3k1r1/8/8/8/8/8/8/RK5R w AHag - 0 1")
+	if got := board.ShredderFEN(); got != "r3k1r1/8/8/8/8/8/8/RK5R w HAga - 0 1" {
+		t.Errorf("ShredderFEN = %s", got)
+	}
+	queenside := Move{From: 1, To: 0, Promotion: PromoteNone}
+	if !IsMoveLegal(&board, queenside.From, queenside.To, queenside.Promotion) {
+		t.Fatal("queenside castling should be legal")
+	}
+	if san := MoveToSAN(&board, queenside); san != "O-O-O" {
+		t.Errorf("SAN = %s", san)
+	}
+	MakeMove(&board, queenside.From, queenside.To, queenside.Promotion)
+	if want := "r3k1r1/8/8/8/8/8/8/2KR3R b ga - 1 1"; board.ShredderFEN() != want {
+		t.Errorf("after O-O-O: %s", board.ShredderFEN())
+	}
+}
+
+func TestXFENInnerRook(t *testing.T) {
+	// two rooks on the kingside: K means the outer one, the inner one needs its file letter
+	outer := mustParseFEN(t, "4k3/8/8/8/8/8/8/4KRR1 w K - 0 1")
+	if outer.CastlingRooks[White][0] != 6 {
+		t.Errorf("K picked rook on %d", outer.CastlingRooks[White][0])
+	}
+	inner := mustParseFEN(t, "4k3/8/8/8/8/8/8/4KRR1 w F - 0 1")
+	if inner.CastlingRooks[White][0] != 5 {
+		t.Errorf("F picked rook on %d", inner.CastlingRooks[White][0])
+	}
+	for _, board := range []Board{outer, inner} {
+		again := mustParseFEN(t, board.FEN())
+		if again != board {
+			t.Errorf("X-FEN round trip changed %s", board.FEN())
+		}
+	}
+	if got := inner.FEN(); got != "4k3/8/8/8/8/8/8/4KRR1 w F - 0 1" {
+		t.Errorf("inner rook X-FEN = %s", got)
+	}
+}
+
+func TestShredderStandardSetup(t *testing.T) {
+	// file letters for the standard rooks don't make the board a Chess960 one
+	board := mustParseFEN(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1")
+	if board.Flags&Chess960 != 0 {
+		t.Error("HAha marked the starting position as Chess960")
+	}
+	if start := mustParseFEN(t, StartingFEN); board != start {
+		t.Errorf("HAha parsed as %s, want %s", board.FEN(), StartingFEN)
+	}
+}
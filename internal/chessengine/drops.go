@@ -0,0 +1,94 @@
+package chess
+
+// Drop moves (crazyhouse, bughouse) are encoded as Move{From: DropOffset + piece, To: square},
+// the same shape clients send in MovePiece
+const DropOffset int8 = 64
+
+func DropMove(piece int, to int8) Move {
+	return Move{From: DropOffset + int8(piece), To: to}
+}
+
+func (m Move) IsDrop() bool {
+	return m.From >= DropOffset
+}
+
+// DropPiece is the piece type being dropped, only meaningful when IsDrop
+func (m Move) DropPiece() int {
+	return int(m.From - DropOffset)
+}
+
+func pocketKey(color int8, piece int, count uint8) uint64 {
+	if count > 16 {
+		count = 16
+	}
+	return zobristPockets[color][piece][count]
+}
+
+// addToPocket changes a pocket count by delta and returns the hash difference
+func (b *Board) addToPocket(color int8, piece int, delta int) uint64 {
+	diff := pocketKey(color, piece, b.Pockets[color][piece])
+	b.Pockets[color][piece] = uint8(int(b.Pockets[color][piece]) + delta)
+	return diff ^ pocketKey(color, piece, b.Pockets[color][piece])
+}
+
+// AddToPocket hands a piece to color, bughouse uses it to pass captures to the partner board
+func (b *Board) AddToPocket(color int8, piece int) {
+	b.Hash ^= b.addToPocket(color, piece, 1)
+}
+
+func makeDrop(board *Board, m Move) Undo {
+	color := board.ActiveColor()
+	piece := m.DropPiece()
+	toBB := Bitboard(1) << m.To
+
+	undo := Undo{
+		Move:            m,
+		MovedPiece:      int8(piece),
+		CapturedPiece:   -1,
+		CaptureSquare:   m.To,
+		CastleSide:      -1,
+		Flags:           board.Flags,
+		EnPassantSquare: board.EnPassantSquare,
+		HalfmoveClock:   board.HalfmoveClock,
+		FullmoveNumber:  board.FullmoveNumber,
+		Hash:            board.Hash,
+		Pockets:         board.Pockets,
+		Promoted:        board.Promoted,
+	}
+
+	newHash := board.Hash
+	if board.EnPassantSquare >= 0 {
+		newHash ^= zobristEnPassant[board.EnPassantSquare]
+		board.EnPassantSquare = -1
+	}
+	newHash ^= board.addToPocket(color, piece, -1)
+
+	*board.pieceBB(color, piece) |= toBB
+	board.Occupied[color] |= toBB
+	newHash ^= zobristPieces[color][piece][m.To]
+
+	board.Flags ^= WhiteToMove
+	newHash ^= zobristSideToMove
+
+	// drops never reset the fifty-move counter
+	board.UpdateMoveCounters(false, false)
+	board.Hash = newHash
+	return undo
+}
+
+// appendDrops adds a drop for every pocket piece on every empty square, pawns stay off the back ranks
+func appendDrops(board *Board, color int8, empty Bitboard, moves []Move) []Move {
+	for piece := Pawn; piece <= Queen; piece++ {
+		if board.Pockets[color][piece] == 0 {
+			continue
+		}
+		targets := empty
+		if piece == Pawn {
+			targets &^= rank1 | rank8
+		}
+		for targets != 0 {
+			moves = append(moves, DropMove(piece, int8(PopLSB(&targets))))
+		}
+	}
+	return moves
+}
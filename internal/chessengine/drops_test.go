@@ -0,0 +1,88 @@
+package chess
+
+import "testing"
+
+func TestCrazyhousePerft(t *testing.T) {
+	cases := []struct {
+		fen   string
+		depth int
+		nodes uint64
+		slow  bool
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", 4, 197281, false},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", 5, 4888832, true},
+		{"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", 1, 301, false},
+		{"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", 2, 75353, false},
+	}
+	for _, tc := range cases {
+		if tc.slow && testing.Short() {
+			continue
+		}
+		board := mustParseFEN(t, tc.fen)
+		board.Flags |= PocketCaptures
+		if got := Perft(&board, tc.depth); got != tc.nodes {
+			t.Errorf("%s: perft(%d) = %d, want %d", tc.fen, tc.depth, got, tc.nodes)
+		}
+		checkHashes(t, &board, 2)
+		checkUnmake(t, &board, 2)
+	}
+}
+
+func TestPocketFENRoundTrip(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		"r1bQ~kb1r/pppp1ppp/2n2n2/4p3/8/8/PPPP1PPP/RNB1KBNR[QPPnp] b KQkq - 0 5",
+	}
+	for _, fen := range fens {
+		board := mustParseFEN(t, fen)
+		if got := board.FEN(); got != fen {
+			t.Errorf("round trip mismatch\n got: %s\nwant: %s", got, fen)
+		}
+	}
+}
+
+func TestCrazyhouseCaptures(t *testing.T) {
+	// the promoted queen on d8 goes back to the pocket as a pawn
+	board := mustParseFEN(t, "r1bQ~kb1r/pppp1ppp/2n2n2/4p3/8/8/PPPP1PPP/RNB1KBNR[] b KQkq - 0 5")
+	board.Flags |= PocketCaptures
+	undo := MakeMove(&board, 60, 59, PromoteNone) // Kxd8
+	if undo.PocketPiece() != Pawn || board.Pockets[Black][Pawn] != 1 || board.Pockets[Black][Queen] != 0 {
+		t.Errorf("pockets after Kxd8: %v", board.Pockets)
+	}
+	if board.Hash != ComputeHash(&board) {
+		t.Error("incremental hash doesn't cover pockets")
+	}
+
+	drop := DropMove(Pawn, 20) // P@e3
+	if !IsMoveLegal(&board, 11, 19, PromoteNone) || IsMoveLegal(&board, drop.From, drop.To, drop.Promotion) {
+		t.Error("white has nothing to drop")
+	}
+	MakeMove(&board, 11, 19, PromoteNone) // d3
+	if san := MoveToSAN(&board, DropMove(Pawn, 20)); san != "@e3" {
+		t.Errorf("pawn drop SAN = %s", san)
+	}
+	if m, err := ParseSAN(&board, "P@e3"); err != nil || m != DropMove(Pawn, 20) {
+		t.Errorf("ParseSAN(P@e3) = %v, %v", m, err)
+	}
+}
+
+func TestDropRules(t *testing.T) {
+	board := mustParseFEN(t, "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1")
+	for _, m := range GenerateLegalMoves(&board) {
+		if m.IsDrop() && (m.To < 8 || m.To >= 56) {
+			t.Errorf("pawn dropped on the back rank: %s", moveToString(m))
+		}
+	}
+
+	// a drop can block a check
+	board = mustParseFEN(t, "4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1")
+	blocks := 0
+	for _, m := range GenerateLegalMoves(&board) {
+		if m.IsDrop() {
+			blocks++
+		}
+	}
+	if blocks != 3 {
+		t.Errorf("expected drops on b1, c1, d1 only, got %d", blocks)
+	}
+}
@@ -2,9 +2,9 @@ package chess
 
 import (
 	"fmt"
-	"log"
 	"math/bits"
 	"math/rand"
+	"strings"
 )
 
 //https://en.wikipedia.org/wiki/Bitboard
@@ -38,17 +38,6 @@ const (
 	rank8 Bitboard = 0xFF00000000000000
 )
 
-var castlingRightsBySquare = [64]uint8{
-	^WQ, 0xFF, 0xFF, 0xFF, ^(WK | WQ), 0xFF, 0xFF, ^WK, // rank 1 (White)
-	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // rank 2
-	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // rank 3
-	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // rank 4
-	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // rank 5
-	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // rank 6
-	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // rank 7
-	^BQ, 0xFF, 0xFF, 0xFF, ^(BK | BQ), 0xFF, 0xFF, ^BK, // rank 8 (Black)
-}
-
 const (
 	WK uint8 = 1 << 0 // White kingside
 	WQ uint8 = 1 << 1 // White queenside
@@ -56,8 +45,23 @@ const (
 	BQ uint8 = 1 << 3 // Black queenside
 
 	WhiteToMove uint8 = 1 << 4
+	Chess960    uint8 = 1 << 5 // castling is encoded as king takes own rook
+
+	DropsAllowed   uint8 = 1 << 6 // pieces in the pocket can be dropped (crazyhouse, bughouse)
+	PocketCaptures uint8 = 1 << 7 // captured pieces go to the capturer's pocket (crazyhouse)
 )
 
+// [color][0 = kingside, 1 = queenside]
+var castlingRights = [2][2]uint8{
+	Black: {BK, BQ},
+	White: {WK, WQ},
+}
+
+var standardCastlingRooks = [2][2]int8{
+	Black: {63, 56},
+	White: {7, 0},
+}
+
 var knightMoves [64]Bitboard
 var kingMoves [64]Bitboard
 
@@ -75,8 +79,9 @@ var zobristTable [6][64]uint64
 var zobristEnPassant [64]uint64
 var zobristSideToMove uint64
 var zobristCastlingRights [2][2]uint64
-var zobristPieces [2][6][64]uint64 // [color][piece][square]
-var zobristCastling [16]uint64     // for all castling flag combinations
+var zobristPieces [2][6][64]uint64  // [color][piece][square]
+var zobristCastling [16]uint64      // for all castling flag combinations
+var zobristPockets [2][5][17]uint64 // [color][piece][count], count 0 hashes to nothing
 
 var rng *rand.Rand
 
@@ -86,9 +91,12 @@ type Board struct {
 	Occupied                                      [2]Bitboard
 	Hash                                          uint64
 	EnPassantSquare                               int8
-	Flags                                         uint8  // bitmask: 1 = WK, 2 = WQ, 4 = BK, 8 = BQ, 16 = WhiteToMove
-	HalfmoveClock                                 uint8  // for 50-move rule
-	FullmoveNumber                                uint16 // increments after black's move
+	Flags                                         uint8       // bitmask: 1 = WK, 2 = WQ, 4 = BK, 8 = BQ, 16 = WhiteToMove, 32 = Chess960
+	HalfmoveClock                                 uint8       // for 50-move rule
+	FullmoveNumber                                uint16      // increments after black's move
+	CastlingRooks                                 [2][2]int8  // rook start squares, [color][0 = kingside, 1 = queenside]
+	Pockets                                       [2][5]uint8 // pieces in hand for drop variants, [color][Pawn..Queen]
+	Promoted                                      Bitboard    // promoted pieces, they go back to the pocket as pawns
 }
 
 func (b *Board) Clone() Board {
@@ -108,8 +116,10 @@ func init() {
 		knightMoves[i] = generateKnightMoves(i)
 		kingMoves[i] = generateKingMoves(i)
 	}
+	initRays()
 	initZobrist()
 	initZobristExtended()
+	initMagics()
 }
 
 func initZobrist() {
@@ -144,6 +154,14 @@ func initZobristExtended() {
 	for i := 0; i < 16; i++ {
 		zobristCastling[i] = rng.Uint64()
 	}
+
+	for color := 0; color < 2; color++ {
+		for piece := 0; piece < 5; piece++ {
+			for count := 1; count < 17; count++ {
+				zobristPockets[color][piece][count] = rng.Uint64()
+			}
+		}
+	}
 }
 
 // === Bitboard Helpers ===
@@ -244,11 +262,15 @@ func NewStartingPosition() Board {
 	b.Flags |= WK | WQ | BK | BQ | WhiteToMove // enable all castling and set side to white
 
 	b.EnPassantSquare = -1
+	b.FullmoveNumber = 1
+	b.CastlingRooks = standardCastlingRooks
 	b.Hash = ComputeHash(&b)
 
 	return b
 }
 
+// slidingAttacks walks the rays square by square. Too slow for the hot path,
+// it only fills the magic tables and serves as the reference in tests.
 func slidingAttacks(sq int, occupied Bitboard, deltas []int) Bitboard {
 	var attacks Bitboard
 	for _, d := range deltas {
@@ -278,6 +300,23 @@ func abs(x int) int {
 	return x
 }
 
+// pieceBB returns the bitboard holding the given piece type
+func (b *Board) pieceBB(color int8, piece int) *Bitboard {
+	switch piece {
+	case Pawn:
+		return &b.Pawns[color]
+	case Knight:
+		return &b.Knights[color]
+	case Bishop:
+		return &b.Bishops[color]
+	case Rook:
+		return &b.Rooks[color]
+	case Queen:
+		return &b.Queens[color]
+	}
+	return &b.Kings[color]
+}
+
 func GetPieceType(board *Board, square int8, color int8) int {
 	bb := Bitboard(1) << square
 	if board.Pawns[color]&bb != 0 {
@@ -301,14 +340,21 @@ func GetPieceType(board *Board, square int8, color int8) int {
 	return -1 // No piece
 }
 
+// IsMoveLegal validates a move coming from the outside world: it has to be one of the
+// generated moves (castling included, which already checks the king's path) and must not
+// leave the mover's king in check
 func IsMoveLegal(board *Board, from, to, promoteTo int8) bool {
-	temp := *board // copy board
-	MakeMove(&temp, from, to, promoteTo)
-
-	attackerColor := int8((board.Flags&WhiteToMove)>>4) ^ 1
+	if from < 0 || from > DropOffset+Queen || to < 0 || to > 63 {
+		return false
+	}
 
-	kingSq := bits.TrailingZeros64(uint64(temp.Kings[attackerColor]))
-	return !IsSquareAttacked(kingSq, &temp, 1-attackerColor)
+	candidate := Move{From: from, To: to, Promotion: promoteTo}
+	for _, m := range generatePseudoLegalMoves(board, make([]Move, 0, 48)) {
+		if m == candidate {
+			return leavesKingSafe(board, m)
+		}
+	}
+	return false
 }
 
 func IsSquareAttacked(sq int, board *Board, attackerColor int8) bool {
@@ -323,31 +369,70 @@ func IsSquareAttacked(sq int, board *Board, attackerColor int8) bool {
 		return true
 	}
 	allOccupied := board.Occupied[White] | board.Occupied[Black]
-	if slidingAttacks(sq, allOccupied, directions["bishop"])&
+	if bishopAttacks(sq, allOccupied)&
 		(board.Bishops[attackerColor]|board.Queens[attackerColor]) != 0 {
 		return true
 	}
-	if slidingAttacks(sq, allOccupied, directions["rook"])&
+	if rookAttacks(sq, allOccupied)&
 		(board.Rooks[attackerColor]|board.Queens[attackerColor]) != 0 {
 		return true
 	}
 	return false
 }
 
-func MakeMove(board *Board, from, to int8, promoteTo int8) Move {
+// MakeMove plays the move in place and returns what UnmakeMove needs to take it back
+func MakeMove(board *Board, from, to int8, promoteTo int8) Undo {
+	if from >= DropOffset {
+		return makeDrop(board, Move{From: from, To: to, Promotion: promoteTo})
+	}
 
-	log.Println(from, to, promoteTo)
 	fromBB := Bitboard(1) << from
 	toBB := Bitboard(1) << to
-	color := int8((board.Flags&WhiteToMove)>>4) ^ 1
+	color := board.ActiveColor()
 	enemyColor := 1 - color
 
 	movingPiece := GetPieceType(board, from, color)
-	log.Println("mving piece", movingPiece, from, color)
 	capturedPiece := GetPieceType(board, to, enemyColor)
 
+	castleSide := int8(-1)
+	if movingPiece == King {
+		castleSide = board.castlingSide(color, from, to)
+	}
+	// rights are lost when the king or a castling rook leaves its square or a rook gets captured
+	rightsMask := board.castlingMask(from) & board.castlingMask(to)
+
+	undo := Undo{
+		Move:            Move{From: from, To: to, Promotion: promoteTo},
+		MovedPiece:      int8(movingPiece),
+		CapturedPiece:   int8(capturedPiece),
+		CaptureSquare:   to,
+		CastleSide:      castleSide,
+		Flags:           board.Flags,
+		EnPassantSquare: board.EnPassantSquare,
+		HalfmoveClock:   board.HalfmoveClock,
+		FullmoveNumber:  board.FullmoveNumber,
+		Hash:            board.Hash,
+		Pockets:         board.Pockets,
+		Promoted:        board.Promoted,
+	}
+
 	newHash := board.Hash // Start incremental hash updates
 
+	// Promoted pieces keep their mark as they move and lose it when captured
+	capturedPromoted := board.Promoted&toBB != 0
+	if board.Promoted&fromBB != 0 {
+		board.Promoted = board.Promoted&^fromBB | toBB
+	} else {
+		board.Promoted &^= toBB
+	}
+	if capturedPiece >= 0 && board.Flags&PocketCaptures != 0 {
+		pocketPiece := capturedPiece
+		if capturedPromoted {
+			pocketPiece = Pawn
+		}
+		newHash ^= board.addToPocket(color, pocketPiece, 1)
+	}
+
 	// Remove moving piece from source
 	if movingPiece >= 0 {
 		newHash ^= zobristPieces[color][movingPiece][from]
@@ -387,10 +472,16 @@ func MakeMove(board *Board, from, to int8, promoteTo int8) Move {
 	// Handle en passant capture
 	if board.EnPassantSquare == to && movingPiece == Pawn {
 		capSq := to - 8
-		if color == White {
+		if color == Black {
 			capSq = to + 8
 		}
 		board.Pawns[enemyColor] &^= Bitboard(1) << capSq
+		board.Occupied[enemyColor] &^= Bitboard(1) << capSq
+		undo.CapturedPiece = Pawn
+		undo.CaptureSquare = capSq
+		if board.Flags&PocketCaptures != 0 {
+			newHash ^= board.addToPocket(color, Pawn, 1)
+		}
 		// Remove captured pawn from hash
 		newHash ^= zobristPieces[enemyColor][Pawn][capSq]
 	}
@@ -402,6 +493,7 @@ func MakeMove(board *Board, from, to int8, promoteTo int8) Move {
 		board.Pawns[color] &^= fromBB
 		// Check for promotion
 		if (color == White && (toBB&rank8) != 0) || (color == Black && (toBB&rank1) != 0) {
+			board.Promoted |= toBB
 			switch promoteTo {
 			case 1:
 				board.Rooks[color] |= toBB
@@ -415,6 +507,9 @@ func MakeMove(board *Board, from, to int8, promoteTo int8) Move {
 			case 4:
 				board.Queens[color] |= toBB
 				finalPiece = Queen
+			case 5:
+				board.Kings[color] |= toBB
+				finalPiece = King
 			default:
 				board.Pawns[color] |= toBB
 			}
@@ -435,11 +530,25 @@ func MakeMove(board *Board, from, to int8, promoteTo int8) Move {
 		board.Queens[color] |= toBB
 	case King:
 		board.Kings[color] &^= fromBB
+		if castleSide >= 0 {
+			// Castling, in Chess960 the king "captures" its rook so both get lifted first
+			rule := board.castlingRule(color, int(castleSide), from)
+			rookFromBB := Bitboard(1) << rule.rook
+			rookToBB := Bitboard(1) << rule.rookTo
+			board.Rooks[color] &^= rookFromBB
+			board.Occupied[color] &^= fromBB | rookFromBB
+			newHash ^= zobristPieces[color][Rook][rule.rook]
+
+			toBB = Bitboard(1) << rule.kingTo
+			to = rule.kingTo
+			board.Rooks[color] |= rookToBB
+			board.Occupied[color] |= rookToBB
+			newHash ^= zobristPieces[color][Rook][rule.rookTo]
+		}
 		board.Kings[color] |= toBB
 	}
 
 	// Add piece to destination in hash
-	log.Println(color, finalPiece, to)
 	newHash ^= zobristPieces[color][finalPiece][to]
 
 	// Update en passant square
@@ -455,12 +564,14 @@ func MakeMove(board *Board, from, to int8, promoteTo int8) Move {
 	}
 
 	// Update occupancy bitboards
-	board.Occupied[color] &^= fromBB
+	if castleSide < 0 {
+		board.Occupied[color] &^= fromBB // castling already lifted the king (its square may hold the rook now)
+	}
 	board.Occupied[color] |= toBB
 	board.Occupied[enemyColor] &^= toBB
 
-	// Update castling rights using lookup table
-	board.Flags &= castlingRightsBySquare[from] & castlingRightsBySquare[to]
+	// Update castling rights
+	board.Flags &= rightsMask
 
 	// Add new castling rights to hash
 	newHash ^= zobristCastling[board.Flags&0x0F]
@@ -473,7 +584,86 @@ func MakeMove(board *Board, from, to int8, promoteTo int8) Move {
 
 	// Update hash
 	board.Hash = newHash
-	return Move{From: from, To: to, Promotion: promoteTo}
+
+	return undo
+}
+
+// Undo is everything MakeMove destroys, enough to restore the board exactly
+type Undo struct {
+	Move            Move
+	MovedPiece      int8
+	CapturedPiece   int8 // -1 when nothing was captured
+	CaptureSquare   int8 // differs from Move.To for en passant
+	CastleSide      int8 // -1 unless the move castled, then 0 = kingside, 1 = queenside
+	Flags           uint8
+	EnPassantSquare int8
+	HalfmoveClock   uint8
+	FullmoveNumber  uint16
+	Hash            uint64
+	Pockets         [2][5]uint8
+	Promoted        Bitboard
+}
+
+// PocketPiece is what the captured piece turns into in a pocket: promoted pieces become pawns again.
+// -1 if the move captured nothing.
+func (u Undo) PocketPiece() int {
+	if u.CapturedPiece < 0 {
+		return -1
+	}
+	if u.Promoted&(Bitboard(1)<<u.CaptureSquare) != 0 {
+		return Pawn
+	}
+	return int(u.CapturedPiece)
+}
+
+// UnmakeMove takes back the move MakeMove returned undo for.
+// Moves have to be unmade in reverse order.
+func UnmakeMove(board *Board, undo Undo) {
+	m := undo.Move
+	fromBB := Bitboard(1) << m.From
+	toBB := Bitboard(1) << m.To
+	color := int8(Black)
+	if undo.Flags&WhiteToMove != 0 {
+		color = White
+	}
+	enemyColor := 1 - color
+
+	if m.IsDrop() {
+		*board.pieceBB(color, m.DropPiece()) &^= toBB
+		board.Occupied[color] &^= toBB
+	} else if undo.CastleSide >= 0 {
+		board.Flags = undo.Flags
+		rule := board.castlingRule(color, int(undo.CastleSide), m.From)
+		kingToBB := Bitboard(1) << rule.kingTo
+		rookToBB := Bitboard(1) << rule.rookTo
+		rookFromBB := Bitboard(1) << rule.rook
+		board.Kings[color] = board.Kings[color]&^kingToBB | fromBB
+		board.Rooks[color] = board.Rooks[color]&^rookToBB | rookFromBB
+		board.Occupied[color] = board.Occupied[color]&^(kingToBB|rookToBB) | fromBB | rookFromBB
+	} else {
+		// Put the moving piece back, a promoted piece turns into the pawn again
+		if finalPiece := GetPieceType(board, m.To, color); finalPiece >= 0 {
+			*board.pieceBB(color, finalPiece) &^= toBB
+		}
+		if undo.MovedPiece >= 0 {
+			*board.pieceBB(color, int(undo.MovedPiece)) |= fromBB
+		}
+		board.Occupied[color] = board.Occupied[color]&^toBB | fromBB
+	}
+
+	if undo.CapturedPiece >= 0 {
+		capBB := Bitboard(1) << undo.CaptureSquare
+		*board.pieceBB(enemyColor, int(undo.CapturedPiece)) |= capBB
+		board.Occupied[enemyColor] |= capBB
+	}
+
+	board.Flags = undo.Flags
+	board.EnPassantSquare = undo.EnPassantSquare
+	board.HalfmoveClock = undo.HalfmoveClock
+	board.FullmoveNumber = undo.FullmoveNumber
+	board.Hash = undo.Hash
+	board.Pockets = undo.Pockets
+	board.Promoted = undo.Promoted
 }
 
 func ComputeHash(b *Board) uint64 {
@@ -517,6 +707,13 @@ func ComputeHash(b *Board) uint64 {
 	// Add castling rights
 	hash ^= zobristCastling[b.Flags&0x0F]
 
+	// Add pocket counts
+	for color := int8(0); color < 2; color++ {
+		for piece := 0; piece < 5; piece++ {
+			hash ^= pocketKey(color, piece, b.Pockets[color][piece])
+		}
+	}
+
 	return hash
 }
 
@@ -577,7 +774,7 @@ func (b *Board) UpdateMoveCounters(capturedPiece bool, pawnMove bool) {
 	}
 
 	// Increment fullmove number after black's move
-	if b.Flags&16 == 0 { // If it was black's turn (now switching to white)
+	if b.Flags&WhiteToMove != 0 { // If it was black's turn (now switching to white)
 		b.FullmoveNumber++
 	}
 }
@@ -591,37 +788,37 @@ func (b *Board) ToByteArray() []byte {
 	return bytes
 }
 
+// ToPGN writes the SAN movetext of moveHistory played from b
 func (b *Board) ToPGN(moveHistory []Move) string {
-	var pgn string
+	var sb strings.Builder
+	board := *b
 	for i, move := range moveHistory {
-		if i%2 == 0 {
-			pgn += fmt.Sprintf("%d. %s ", i/2+1, moveToString(move))
-		} else {
-			pgn += fmt.Sprintf("%s ", moveToString(move))
+		white := board.Flags&WhiteToMove != 0
+		if white {
+			fmt.Fprintf(&sb, "%d. ", board.FullmoveNumber)
+		} else if i == 0 {
+			fmt.Fprintf(&sb, "%d... ", board.FullmoveNumber)
 		}
+		sb.WriteString(MoveToSAN(&board, move))
+		sb.WriteByte(' ')
+		MakeMove(&board, move.From, move.To, move.Promotion)
 	}
-	return pgn
+	return sb.String()
 }
 
+// moveToString writes coordinate notation, e.g. "e2e4" or "e7e8q"
 func moveToString(move Move) string {
+	if move.IsDrop() {
+		return string(fenPieces[move.DropPiece()]-('a'-'A')) + "@" + squareToString(move.To)
+	}
 	from := squareToString(move.From)
 	to := squareToString(move.To)
-	promo := ""
-	switch move.Promotion {
-	case Rook:
-		promo = "R"
-	case Knight:
-		promo = "N"
-	case Bishop:
-		promo = "B"
-	case Queen:
-		promo = "Q"
-	}
+	promo := strings.ToLower(promotionLetters[move.Promotion])
 	return from + to + promo
 }
 
 func squareToString(s int8) string {
 	file := s % 8
 	rank := s / 8
-	return string('a'+file) + string('1'+rank)
+	return string(rune('a'+file)) + string(rune('1'+rank))
 }
@@ -0,0 +1,131 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//https://www.chessprogramming.org/Extended_Position_Description
+
+// EPD is a position with its operations, e.g. bm (best moves), am (avoid moves) and id
+type EPD struct {
+	Board Board
+	Ops   map[string][]string // opcode to operands, quotes removed
+}
+
+// ParseEPD reads one EPD record: the first four FEN fields followed by "opcode operands;" operations.
+// hmvc and fmvn operations set the move counters.
+func ParseEPD(line string) (EPD, error) {
+	epd := EPD{Ops: make(map[string][]string)}
+
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return epd, fmt.Errorf("epd: expected at least 4 fields, got %d", len(fields))
+	}
+	board, err := ParseFEN(strings.Join(fields[:4], " "))
+	if err != nil {
+		return epd, fmt.Errorf("epd: %w", err)
+	}
+	epd.Board = board
+
+	// the operations start after the fourth field
+	rest := line
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[strings.IndexAny(rest+" ", " \t"):]
+	}
+
+	for _, op := range splitEPDOps(rest) {
+		tokens, err := epdTokens(op)
+		if err != nil {
+			return epd, err
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		epd.Ops[tokens[0]] = tokens[1:]
+	}
+
+	if v, ok := epd.Ops["hmvc"]; ok && len(v) > 0 {
+		n, err := strconv.ParseUint(v[0], 10, 8)
+		if err != nil {
+			return epd, fmt.Errorf("epd: invalid hmvc %q", v[0])
+		}
+		epd.Board.HalfmoveClock = uint8(n)
+	}
+	if v, ok := epd.Ops["fmvn"]; ok && len(v) > 0 {
+		n, err := strconv.ParseUint(v[0], 10, 16)
+		if err != nil {
+			return epd, fmt.Errorf("epd: invalid fmvn %q", v[0])
+		}
+		epd.Board.FullmoveNumber = uint16(n)
+	}
+	return epd, nil
+}
+
+// ID is the id operation, empty when missing
+func (e EPD) ID() string {
+	if v := e.Ops["id"]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Moves parses the SAN operands of opcode (bm, am) on the EPD's position
+func (e EPD) Moves(opcode string) ([]Move, error) {
+	var moves []Move
+	for _, san := range e.Ops[opcode] {
+		board := e.Board
+		m, err := ParseSAN(&board, san)
+		if err != nil {
+			return nil, fmt.Errorf("epd: %s %q: %w", opcode, san, err)
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// splitEPDOps splits on semicolons outside of quoted strings
+func splitEPDOps(s string) []string {
+	var ops []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				ops = append(ops, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		ops = append(ops, s[start:])
+	}
+	return ops
+}
+
+func epdTokens(op string) ([]string, error) {
+	var tokens []string
+	for op = strings.TrimSpace(op); op != ""; op = strings.TrimSpace(op) {
+		if op[0] == '"' {
+			end := strings.IndexByte(op[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("epd: unterminated string in %q", op)
+			}
+			tokens = append(tokens, op[1:end+1])
+			op = op[end+2:]
+			continue
+		}
+		end := strings.IndexAny(op, " \t")
+		if end < 0 {
+			end = len(op)
+		}
+		tokens = append(tokens, op[:end])
+		op = op[end:]
+	}
+	return tokens, nil
+}
@@ -0,0 +1,59 @@
+package chess
+
+import "testing"
+
+func TestParseEPD(t *testing.T) {
+	epd, err := ParseEPD(`2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001; mate"; c0 "a b";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epd.ID() != "WAC.001; mate" {
+		t.Errorf("id: got %q", epd.ID())
+	}
+	if got := epd.Ops["c0"]; len(got) != 1 || got[0] != "a b" {
+		t.Errorf("c0: got %q", got)
+	}
+	bm, err := epd.Moves("bm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bm) != 1 || bm[0] != (Move{From: mustSquare("g3"), To: mustSquare("g6")}) {
+		t.Errorf("bm: got %+v", bm)
+	}
+	if am, err := epd.Moves("am"); err != nil || len(am) != 0 {
+		t.Errorf("am: got %+v, %v", am, err)
+	}
+}
+
+func TestParseEPDCounters(t *testing.T) {
+	epd, err := ParseEPD("4k3/8/8/8/8/8/4P3/4K3 w - - hmvc 12; fmvn 40; bm e4 e3;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epd.Board.HalfmoveClock != 12 || epd.Board.FullmoveNumber != 40 {
+		t.Errorf("counters: got %d %d", epd.Board.HalfmoveClock, epd.Board.FullmoveNumber)
+	}
+	if bm, err := epd.Moves("bm"); err != nil || len(bm) != 2 {
+		t.Errorf("bm: got %+v, %v", bm, err)
+	}
+}
+
+func TestParseEPDErrors(t *testing.T) {
+	for _, line := range []string{
+		"4k3/8/8/8 w",
+		"4k3/8/8/8/8/8/8/4K3 w - - id \"unterminated;",
+		"4k3/8/8/8/8/8/8/4K3 w - - hmvc lots;",
+	} {
+		if _, err := ParseEPD(line); err == nil {
+			t.Errorf("%q: expected an error", line)
+		}
+	}
+
+	epd, err := ParseEPD("4k3/8/8/8/8/8/8/4K3 w - - bm Qh5;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := epd.Moves("bm"); err == nil {
+		t.Error("expected an error for a bm that isn't a legal move")
+	}
+}
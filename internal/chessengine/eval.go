@@ -0,0 +1,124 @@
+package chess
+
+//https://www.chessprogramming.org/Simplified_Evaluation_Function
+
+var pieceValues = [6]int{100, 320, 330, 500, 900, 0} // indexed by piece type, kings aren't counted
+
+// piece-square tables from white's point of view, a8 first so they read like a board
+var pieceSquareTables = [6][64]int{
+	Pawn: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	Knight: {
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	},
+	Bishop: {
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	},
+	Rook: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	},
+	Queen: {
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	},
+	King: {
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	},
+}
+
+// once the heavy pieces are gone the king should head for the center
+var kingEndgameTable = [64]int{
+	-50, -40, -30, -20, -20, -30, -40, -50,
+	-30, -20, -10, 0, 0, -10, -20, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -30, 0, 0, 0, 0, -30, -30,
+	-50, -30, -30, -30, -30, -30, -30, -50,
+}
+
+// non-pawn material of both sides below which the endgame king table is used, about a rook and a minor each
+const endgameMaterial = 2 * (500 + 330)
+
+// Evaluate scores the position in centipawns from the side to move's point of view
+func Evaluate(board *Board) int {
+	var score [2]int
+	nonPawn := 0
+
+	for color := int8(0); color < 2; color++ {
+		for piece := Pawn; piece <= Queen; piece++ {
+			for bb := *board.pieceBB(color, piece); bb != 0; {
+				sq := PopLSB(&bb)
+				score[color] += pieceValues[piece] + pieceSquareTables[piece][tableIndex(color, sq)]
+				if piece != Pawn {
+					nonPawn += pieceValues[piece]
+				}
+			}
+			score[color] += int(board.Pockets[color][piece]) * pieceValues[piece]
+		}
+	}
+
+	kingTable := &pieceSquareTables[King]
+	if nonPawn <= endgameMaterial {
+		kingTable = &kingEndgameTable
+	}
+	for color := int8(0); color < 2; color++ {
+		for bb := board.Kings[color]; bb != 0; {
+			score[color] += kingTable[tableIndex(color, PopLSB(&bb))]
+		}
+	}
+
+	color := board.ActiveColor()
+	return score[color] - score[1-color]
+}
+
+// tableIndex maps a square to the a8-first tables, mirrored for black
+func tableIndex(color int8, sq int) int {
+	if color == White {
+		return sq ^ 56
+	}
+	return sq
+}
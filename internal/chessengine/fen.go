@@ -0,0 +1,332 @@
+package chess
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var fenPieces = [6]byte{'p', 'n', 'b', 'r', 'q', 'k'} // indexed by piece type
+
+// ParseFEN builds a Board from Forsyth-Edwards Notation.
+// The move counters are optional and default to "0 1".
+// Castling accepts KQkq as well as X-FEN and Shredder-FEN rook files; any castling
+// right that isn't the standard king and rook setup marks the board as Chess960.
+// Crazyhouse pockets follow the placement in brackets ("...RNBQKBNR[Qp]"), '~' marks promoted pieces.
+func ParseFEN(fen string) (Board, error) {
+	var b Board
+	fields := strings.Fields(fen)
+	if len(fields) != 4 && len(fields) != 6 {
+		return b, fmt.Errorf("fen: expected 4 or 6 fields, got %d", len(fields))
+	}
+
+	// Pockets
+	placement := fields[0]
+	if open := strings.IndexByte(placement, '['); open >= 0 {
+		if !strings.HasSuffix(placement, "]") {
+			return b, fmt.Errorf("fen: unterminated pocket in %q", placement)
+		}
+		for _, c := range placement[open+1 : len(placement)-1] {
+			color := int8(Black)
+			if c >= 'A' && c <= 'Z' {
+				color = White
+				c += 'a' - 'A'
+			}
+			piece := strings.IndexByte(string(fenPieces[:Queen+1]), byte(c))
+			if piece < 0 {
+				return b, fmt.Errorf("fen: invalid pocket piece %q", c)
+			}
+			b.Pockets[color][piece]++
+		}
+		b.Flags |= DropsAllowed
+		placement = placement[:open]
+	}
+
+	// Piece placement, from rank 8 down to rank 1
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return b, fmt.Errorf("fen: expected 8 ranks, got %d", len(ranks))
+	}
+	for i, rankStr := range ranks {
+		rank := 7 - i
+		file := 0
+		for _, c := range rankStr {
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+			if c == '~' && file > 0 {
+				b.Promoted |= Bitboard(1) << (rank*8 + file - 1)
+				continue
+			}
+			if file > 7 {
+				return b, fmt.Errorf("fen: rank %d is too long", rank+1)
+			}
+
+			color := int8(Black)
+			if c >= 'A' && c <= 'Z' {
+				color = White
+				c += 'a' - 'A'
+			}
+			piece := strings.IndexByte(string(fenPieces[:]), byte(c))
+			if piece < 0 {
+				return b, fmt.Errorf("fen: invalid piece %q", c)
+			}
+			b.setPiece(color, piece, int8(rank*8+file))
+			file++
+		}
+		if file != 8 {
+			return b, fmt.Errorf("fen: rank %d has %d files", rank+1, file)
+		}
+	}
+	if CountBits(b.Kings[White]) != 1 || CountBits(b.Kings[Black]) != 1 {
+		return b, fmt.Errorf("fen: each side needs exactly one king")
+	}
+
+	// Side to move
+	switch fields[1] {
+	case "w":
+		b.Flags |= WhiteToMove
+	case "b":
+	default:
+		return b, fmt.Errorf("fen: invalid side to move %q", fields[1])
+	}
+
+	// Castling rights
+	b.CastlingRooks = standardCastlingRooks
+	if fields[2] != "-" {
+		for _, c := range fields[2] {
+			if err := b.parseCastlingRight(c); err != nil {
+				return b, fmt.Errorf("fen: invalid castling rights %q: %v", fields[2], err)
+			}
+		}
+	}
+
+	// En passant target, only right behind a pawn of the side not to move that just double-stepped
+	b.EnPassantSquare = -1
+	if fields[3] != "-" {
+		sq, err := ParseSquare(fields[3])
+		if err != nil || !b.validEnPassant(sq) {
+			return b, fmt.Errorf("fen: invalid en passant square %q", fields[3])
+		}
+		b.EnPassantSquare = sq
+	}
+
+	// Move counters
+	b.FullmoveNumber = 1
+	if len(fields) == 6 {
+		halfmove, err := strconv.ParseUint(fields[4], 10, 8)
+		if err != nil {
+			return b, fmt.Errorf("fen: invalid halfmove clock %q", fields[4])
+		}
+		fullmove, err := strconv.ParseUint(fields[5], 10, 16)
+		if err != nil || fullmove == 0 {
+			return b, fmt.Errorf("fen: invalid fullmove number %q", fields[5])
+		}
+		b.HalfmoveClock = uint8(halfmove)
+		b.FullmoveNumber = uint16(fullmove)
+	}
+
+	b.Hash = ComputeHash(&b)
+	return b, nil
+}
+
+// FEN writes the board as Forsyth-Edwards Notation
+func (b *Board) FEN() string {
+	var sb strings.Builder
+
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			sq := int8(rank*8 + file)
+			c := b.pieceChar(sq)
+			if c == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(c)
+			if b.Flags&DropsAllowed != 0 && b.Promoted&(Bitboard(1)<<sq) != 0 {
+				sb.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	if b.Flags&DropsAllowed != 0 {
+		sb.WriteByte('[')
+		for _, color := range []int8{White, Black} {
+			for piece := Queen; piece >= Pawn; piece-- {
+				c := fenPieces[piece]
+				if color == White {
+					c -= 'a' - 'A'
+				}
+				sb.WriteString(strings.Repeat(string(c), int(b.Pockets[color][piece])))
+			}
+		}
+		sb.WriteByte(']')
+	}
+
+	if b.Flags&WhiteToMove != 0 {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	sb.WriteString(b.castlingString(false))
+
+	if b.EnPassantSquare >= 0 {
+		sb.WriteString(" " + squareToString(b.EnPassantSquare))
+	} else {
+		sb.WriteString(" -")
+	}
+
+	fmt.Fprintf(&sb, " %d %d", b.HalfmoveClock, b.FullmoveNumber)
+	return sb.String()
+}
+
+// ShredderFEN is FEN with castling rights written as rook files ("HAha"),
+// the notation most Chess960 software understands
+func (b *Board) ShredderFEN() string {
+	fields := strings.Fields(b.FEN())
+	fields[2] = b.castlingString(true)
+	return strings.Join(fields, " ")
+}
+
+// castlingString writes KQkq, switching to the rook file (X-FEN) when the castling rook
+// isn't the outermost one on its side, or always in Shredder style
+func (b *Board) castlingString(shredder bool) string {
+	castling := ""
+	for _, color := range []int8{White, Black} {
+		for side := 0; side < 2; side++ {
+			if b.Flags&castlingRights[color][side] == 0 {
+				continue
+			}
+			rook := b.CastlingRooks[color][side]
+			letter := byte("KQ"[side])
+			if shredder || (b.Flags&Chess960 != 0 && rook != b.outermostRook(color, side)) {
+				letter = 'A' + byte(rook%8)
+			}
+			if color == Black {
+				letter += 'a' - 'A'
+			}
+			castling += string(letter)
+		}
+	}
+	if castling == "" {
+		return "-"
+	}
+	return castling
+}
+
+func (b *Board) parseCastlingRight(c rune) error {
+	color := int8(White)
+	if c >= 'a' && c <= 'z' {
+		color = Black
+		c -= 'a' - 'A'
+	}
+	backRank := int8(0)
+	if color == Black {
+		backRank = 56
+	}
+	if b.Kings[color]&(rank1<<backRank) == 0 {
+		return fmt.Errorf("king not on its back rank")
+	}
+	kingFile := int8(bits.TrailingZeros64(uint64(b.Kings[color]))) % 8
+
+	side, rook := 0, int8(-1)
+	switch {
+	case c == 'K':
+		rook = b.outermostRook(color, 0)
+	case c == 'Q':
+		side, rook = 1, b.outermostRook(color, 1)
+	case c >= 'A' && c <= 'H':
+		rook = backRank + int8(c-'A')
+		if int8(c-'A') < kingFile {
+			side = 1
+		}
+	default:
+		return fmt.Errorf("unknown castling letter %q", c)
+	}
+	if rook < 0 || b.Rooks[color]&(Bitboard(1)<<rook) == 0 || rook%8 == kingFile {
+		return fmt.Errorf("no castling rook for %q", c)
+	}
+
+	b.Flags |= castlingRights[color][side]
+	b.CastlingRooks[color][side] = rook
+	if kingFile != 4 || rook != standardCastlingRooks[color][side] {
+		b.Flags |= Chess960
+	}
+	return nil
+}
+
+// outermostRook finds the rook furthest from the king on the given side of the back rank, or -1
+func (b *Board) outermostRook(color int8, side int) int8 {
+	backRank := int8(0)
+	if color == Black {
+		backRank = 56
+	}
+	kingFile := int8(bits.TrailingZeros64(uint64(b.Kings[color]))) % 8
+	if side == 0 {
+		for file := int8(7); file > kingFile; file-- {
+			if b.Rooks[color]&(Bitboard(1)<<(backRank+file)) != 0 {
+				return backRank + file
+			}
+		}
+	} else {
+		for file := int8(0); file < kingFile; file++ {
+			if b.Rooks[color]&(Bitboard(1)<<(backRank+file)) != 0 {
+				return backRank + file
+			}
+		}
+	}
+	return -1
+}
+
+// ParseSquare converts algebraic coordinates like "e4" into a square index
+func ParseSquare(s string) (int8, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return -1, fmt.Errorf("invalid square %q", s)
+	}
+	return int8(s[1]-'1')*8 + int8(s[0]-'a'), nil
+}
+
+// pieceChar returns the FEN letter for the piece on sq, or 0 for an empty square
+func (b *Board) pieceChar(sq int8) byte {
+	for color := int8(0); color < 2; color++ {
+		if piece := GetPieceType(b, sq, color); piece >= 0 {
+			if color == White {
+				return fenPieces[piece] - ('a' - 'A')
+			}
+			return fenPieces[piece]
+		}
+	}
+	return 0
+}
+
+func (b *Board) validEnPassant(sq int8) bool {
+	us := b.ActiveColor()
+	pawnSq, rank := sq-8, int8(5)
+	if us == Black {
+		pawnSq, rank = sq+8, 2
+	}
+	occupied := b.Occupied[White] | b.Occupied[Black]
+	return sq/8 == rank && occupied&(Bitboard(1)<<sq) == 0 && b.Pawns[1-us]&(Bitboard(1)<<pawnSq) != 0
+}
+
+func (b *Board) setPiece(color int8, piece int, sq int8) {
+	bb := Bitboard(1) << sq
+	*b.pieceBB(color, piece) |= bb
+	b.Occupied[color] |= bb
+}
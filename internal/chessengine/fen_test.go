@@ -0,0 +1,81 @@
+package chess
+
+import "testing"
+
+func mustParseFEN(t testing.TB, fen string) Board {
+	t.Helper()
+	board, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q): %v", fen, err)
+	}
+	return board
+}
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		StartingFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"4k3/8/8/8/8/8/8/4K2R b K - 57 120",
+	}
+	for _, fen := range fens {
+		board := mustParseFEN(t, fen)
+		if got := board.FEN(); got != fen {
+			t.Errorf("round trip mismatch\n got: %s\nwant: %s", got, fen)
+		}
+		if board.Hash != ComputeHash(&board) {
+			t.Errorf("%s: hash not computed", fen)
+		}
+	}
+}
+
+func TestParseFENMatchesStartingPosition(t *testing.T) {
+	parsed := mustParseFEN(t, StartingFEN)
+	start := NewStartingPosition()
+	if parsed != start {
+		t.Errorf("parsed starting FEN differs from NewStartingPosition\n got: %+v\nwant: %+v", parsed, start)
+	}
+	if got := start.FEN(); got != StartingFEN {
+		t.Errorf("starting position FEN = %s", got)
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+	board := NewStartingPosition()
+	MakeMove(&board, 12, 28, PromoteNone) // e4
+	if want := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"; board.FEN() != want {
+		t.Errorf("after e4: %s", board.FEN())
+	}
+	MakeMove(&board, 57, 42, PromoteNone) // Nc6
+	if want := "r1bqkbnr/pppppppp/2n5/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 1 2"; board.FEN() != want {
+		t.Errorf("after Nc6: %s", board.FEN())
+	}
+}
+
+func TestParseFENErrors(t *testing.T) {
+	bad := []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/ppppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQxq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e6 0 1",   // wrong rank for black to move
+		"rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e3 0 2",   // wrong rank for white to move
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq e3 0 1",     // no pawn in front
+		"rnbqkbnr/pppp1ppp/8/4n3/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 2",   // knight in front, not a pawn
+		"rnbqkbnr/pppp1ppp/8/3Pp3/8/8/PPP1PPPP/RNBQKBNR w KQkq d6 0 2",  // own pawn in front
+		"rnbqkbnr/pppp1ppp/4b3/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 2", // target square taken
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQQBNR w KQkq - 0 1",
+	}
+	for _, fen := range bad {
+		if _, err := ParseFEN(fen); err == nil {
+			t.Errorf("ParseFEN(%q) succeeded", fen)
+		}
+	}
+}
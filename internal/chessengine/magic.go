@@ -0,0 +1,83 @@
+package chess
+
+//https://www.chessprogramming.org/Magic_Bitboards
+
+type magicEntry struct {
+	mask    Bitboard // relevant occupancy, board edges excluded
+	magic   uint64
+	shift   uint8
+	attacks []Bitboard
+}
+
+var rookMagics [64]magicEntry
+var bishopMagics [64]magicEntry
+
+func bishopAttacks(sq int, occupied Bitboard) Bitboard {
+	m := &bishopMagics[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+func rookAttacks(sq int, occupied Bitboard) Bitboard {
+	m := &rookMagics[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+func (m *magicEntry) index(occupied Bitboard) uint64 {
+	return (uint64(occupied&m.mask) * m.magic) >> m.shift
+}
+
+// initMagics fills the attack tables from the checked in magic numbers,
+// TestGenerateMagics searches new ones
+func initMagics() {
+	for sq := 0; sq < 64; sq++ {
+		rookMask, bishopMask := magicMasks(sq)
+		rookMagics[sq] = newMagicEntry(sq, rookMask, rookMagicNumbers[sq])
+		bishopMagics[sq] = newMagicEntry(sq, bishopMask, bishopMagicNumbers[sq])
+		if !rookMagics[sq].fill(sq, directions["rook"]) || !bishopMagics[sq].fill(sq, directions["bishop"]) {
+			panic("chess: magic number collides on " + squareToString(int8(sq)))
+		}
+	}
+}
+
+// magicMasks are the relevant occupancies of a square, board edges excluded
+func magicMasks(sq int) (rook, bishop Bitboard) {
+	rankEdges := (rank1 | rank8) &^ (rank1 << (8 * (sq / 8)))
+	fileEdges := (fileA | fileH) &^ (fileA << (sq % 8))
+
+	rook = slidingAttacks(sq, 0, directions["rook"]) &^ (rankEdges | fileEdges)
+	bishop = slidingAttacks(sq, 0, directions["bishop"]) &^ (rank1 | rank8 | fileA | fileH)
+	return rook, bishop
+}
+
+func newMagicEntry(sq int, mask Bitboard, magic uint64) magicEntry {
+	relevantBits := CountBits(mask)
+	return magicEntry{
+		mask:    mask,
+		magic:   magic,
+		shift:   uint8(64 - relevantBits),
+		attacks: make([]Bitboard, 1<<relevantBits),
+	}
+}
+
+// fill stores the attacks of every occupancy subset of the mask, false when
+// two subsets with different attacks share a slot
+func (m *magicEntry) fill(sq int, deltas []int) bool {
+	clear(m.attacks)
+	used := make([]bool, len(m.attacks))
+
+	// enumerate all subsets of the mask (Carry-Rippler trick)
+	for subset := Bitboard(0); ; {
+		idx := m.index(subset)
+		attacks := slidingAttacks(sq, subset, deltas)
+		if !used[idx] {
+			used[idx] = true
+			m.attacks[idx] = attacks
+		} else if m.attacks[idx] != attacks {
+			return false
+		}
+		subset = (subset - m.mask) & m.mask
+		if subset == 0 {
+			return true
+		}
+	}
+}
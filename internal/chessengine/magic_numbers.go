@@ -0,0 +1,41 @@
+// Code generated by TestGenerateMagics; DO NOT EDIT.
+
+package chess
+
+var rookMagicNumbers = [64]uint64{
+	0x008000801c204000, 0x0040002000401000, 0x1080081000200080, 0x090010010008610c,
+	0x0100050008000210, 0x4100010082040008, 0x4100008200010044, 0x008001000030ca80,
+	0x0403800040058028, 0x0000401000402008, 0x0041001020004105, 0x0022000842002010,
+	0x2000800800040082, 0x0112001044020048, 0x0000808001000200, 0x5002002441008412,
+	0x0001a18000804000, 0x0010004020004000, 0x402000802080100a, 0x4000828008009002,
+	0x0200808008000400, 0x0001010004000208, 0x0401040008015042, 0x0056020020489104,
+	0x8000400080008020, 0x0840200040005004, 0x0002004600158020, 0x1c01022100100008,
+	0x0808000404004020, 0x2024004480020080, 0x0000020400010810, 0x0021800080004100,
+	0x0180204000800090, 0x2010002000404004, 0x0140100080802000, 0x4060800800801000,
+	0x0201000801000410, 0xa214002024011048, 0x0000020804000110, 0x40040e408a00040b,
+	0x80c0008000408028, 0x0000d0006001c000, 0x0080410160030050, 0x0008010200101000,
+	0x3001000800110005, 0x400c000810020200, 0x40001a25080400d0, 0xc001000480490012,
+	0x0002010020508a00, 0x4110804018200480, 0x1080200811004100, 0x00205001000a2300,
+	0x0200080080040080, 0x2408020080040080, 0x0820100201080400, 0x0000010c10c0a200,
+	0x0101408a20120102, 0x2000410014208202, 0x0050084020010011, 0x8081050020d00009,
+	0x0002008804116002, 0x2002001004080102, 0x080900241d820001, 0x0020014121040886,
+}
+
+var bishopMagicNumbers = [64]uint64{
+	0x28100a28c1040100, 0x0004480801022802, 0x00a820c702002010, 0x0a020a0208000080,
+	0xa001104001202040, 0x01208220a028001a, 0x0041080802080180, 0x205c144104104004,
+	0x0000044404080208, 0x5240109041005084, 0x0400040122060000, 0x0002082088200400,
+	0x0000c11040080140, 0x0000220210048200, 0x0200010441444000, 0x0010020842080401,
+	0x8210152202820800, 0x0020030808108090, 0x3008009013204230, 0x0004041809411021,
+	0x4122000404a20808, 0x0202040288010802, 0x0002024901212100, 0x0109000200410441,
+	0x0114400220e20c39, 0x00a1a080100a0e00, 0x2400380144080020, 0x0004080000202140,
+	0x090a840011802000, 0x00080200304100a0, 0x0024410000809005, 0x0000810400212804,
+	0x8004108420410460, 0x8001101040481110, 0x0000220100081806, 0xa080080800520a00,
+	0x0040020220220080, 0x0001085200030105, 0x00021a0400404441, 0x40060605400a1040,
+	0x1402100404342209, 0x004080a450402000, 0x1000402401001000, 0x0800022019004800,
+	0x800002120a040400, 0x0090101000205040, 0x0020024202230041, 0x0024080081084030,
+	0x0480880888040240, 0x0204208208200a00, 0x2000090401040000, 0x0102200084040000,
+	0x0000001082020212, 0x20040820b8408208, 0x401104900c144012, 0x0a2002020c430000,
+	0x1480820500824080, 0x0488004048080904, 0x80c2820104010441, 0x0008881802841100,
+	0x0044001851220200, 0x0800040528109500, 0x1310260808080098, 0x0404010404008200,
+}
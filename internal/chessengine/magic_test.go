@@ -0,0 +1,148 @@
+package chess
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"math/bits"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+var updateMagics = flag.Bool("update", false, "search new magic numbers into magic_numbers.go")
+
+const magicSeed = 0x5EED5EED
+
+// TestGenerateMagics writes magic_numbers.go: go test -run TestGenerateMagics -update
+func TestGenerateMagics(t *testing.T) {
+	if !*updateMagics {
+		t.Skip("run with -update to search the magic numbers again")
+	}
+	magicRng := rand.New(rand.NewSource(magicSeed))
+	var rooks, bishops [64]uint64
+	for sq := 0; sq < 64; sq++ {
+		rookMask, bishopMask := magicMasks(sq)
+		rooks[sq] = findMagic(sq, rookMask, directions["rook"], magicRng)
+		bishops[sq] = findMagic(sq, bishopMask, directions["bishop"], magicRng)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by TestGenerateMagics; DO NOT EDIT.\n\npackage chess\n")
+	for _, table := range []struct {
+		name    string
+		numbers [64]uint64
+	}{{"rook", rooks}, {"bishop", bishops}} {
+		fmt.Fprintf(&buf, "\nvar %sMagicNumbers = [64]uint64{\n", table.name)
+		for i, magic := range table.numbers {
+			fmt.Fprintf(&buf, "0x%016x,", magic)
+			if i%4 == 3 {
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteByte(' ')
+			}
+		}
+		buf.WriteString("}\n")
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("magic_numbers.go", src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// findMagic searches for a multiplier that maps every occupancy subset of mask
+// to a slot without destructive collisions
+func findMagic(sq int, mask Bitboard, deltas []int, magicRng *rand.Rand) uint64 {
+	for {
+		// sparse candidates find magics much faster
+		magic := magicRng.Uint64() & magicRng.Uint64() & magicRng.Uint64()
+		if bits.OnesCount64((uint64(mask)*magic)&0xFF00000000000000) < 6 {
+			continue
+		}
+		entry := newMagicEntry(sq, mask, magic)
+		if entry.fill(sq, deltas) {
+			return magic
+		}
+	}
+}
+
+func TestMagicAttacksMatchReference(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for sq := 0; sq < 64; sq++ {
+		for i := 0; i < 2000; i++ {
+			occupied := Bitboard(r.Uint64() & r.Uint64())
+			if got, want := rookAttacks(sq, occupied), slidingAttacks(sq, occupied, directions["rook"]); got != want {
+				t.Fatalf("rook on %s, occupied %x: got %x, want %x", squareToString(int8(sq)), occupied, got, want)
+			}
+			if got, want := bishopAttacks(sq, occupied), slidingAttacks(sq, occupied, directions["bishop"]); got != want {
+				t.Fatalf("bishop on %s, occupied %x: got %x, want %x", squareToString(int8(sq)), occupied, got, want)
+			}
+		}
+	}
+}
+
+// isSquareAttackedReference is IsSquareAttacked as it was before the magic tables
+func isSquareAttackedReference(sq int, board *Board, attackerColor int8) bool {
+	mask := Bitboard(1) << sq
+	if knightMoves[sq]&board.Knights[attackerColor] != 0 {
+		return true
+	}
+	if kingMoves[sq]&board.Kings[attackerColor] != 0 {
+		return true
+	}
+	if PawnAttacks(mask, board.Pawns[attackerColor], attackerColor == Black) != 0 {
+		return true
+	}
+	allOccupied := board.Occupied[White] | board.Occupied[Black]
+	if slidingAttacks(sq, allOccupied, directions["bishop"])&
+		(board.Bishops[attackerColor]|board.Queens[attackerColor]) != 0 {
+		return true
+	}
+	return slidingAttacks(sq, allOccupied, directions["rook"])&
+		(board.Rooks[attackerColor]|board.Queens[attackerColor]) != 0
+}
+
+func benchmarkAttacked(b *testing.B, attacked func(int, *Board, int8) bool) {
+	board := mustParseFEN(b, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for sq := 0; sq < 64; sq++ {
+			attacked(sq, &board, int8(sq&1))
+		}
+	}
+}
+
+func BenchmarkIsSquareAttackedMagic(b *testing.B) {
+	benchmarkAttacked(b, IsSquareAttacked)
+}
+
+func BenchmarkIsSquareAttackedReference(b *testing.B) {
+	benchmarkAttacked(b, isSquareAttackedReference)
+}
+
+// legality checks the way the generator does them, king square tested after every move
+func benchmarkLegality(b *testing.B, attacked func(int, *Board, int8) bool) {
+	board := mustParseFEN(b, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	moves := generatePseudoLegalMoves(&board, nil)
+	color := board.ActiveColor()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, m := range moves {
+			undo := MakeMove(&board, m.From, m.To, m.Promotion)
+			attacked(bits.TrailingZeros64(uint64(board.Kings[color])), &board, 1-color)
+			UnmakeMove(&board, undo)
+		}
+	}
+}
+
+func BenchmarkLegalityMagic(b *testing.B) {
+	benchmarkLegality(b, IsSquareAttacked)
+}
+
+func BenchmarkLegalityReference(b *testing.B) {
+	benchmarkLegality(b, isSquareAttackedReference)
+}
@@ -0,0 +1,292 @@
+package chess
+
+import "math/bits"
+
+// Promotion codes as understood by MakeMove (and sent by clients in MovePiece)
+const (
+	PromoteNone   int8 = 0
+	PromoteRook   int8 = 1
+	PromoteKnight int8 = 2
+	PromoteBishop int8 = 3
+	PromoteQueen  int8 = 4
+	PromoteKing   int8 = 5 // antichess only
+)
+
+var promotionCodes = [4]int8{PromoteQueen, PromoteRook, PromoteBishop, PromoteKnight}
+
+type castlingRule struct {
+	king, kingTo int8
+	rook, rookTo int8
+	empty        Bitboard // squares that must be vacant, king and castling rook aside
+	safe         Bitboard // squares the king stands on or crosses, must not be attacked
+}
+
+// castlingRule describes castling to the given side with the king on king.
+// Works for any Chess960 setup: king and rook always end on the g/f or c/d files.
+func (b *Board) castlingRule(color int8, side int, king int8) castlingRule {
+	backRank := int8(0)
+	if color == Black {
+		backRank = 56
+	}
+	rule := castlingRule{king: king, rook: b.CastlingRooks[color][side]}
+	if side == 0 {
+		rule.kingTo, rule.rookTo = backRank+6, backRank+5
+	} else {
+		rule.kingTo, rule.rookTo = backRank+2, backRank+3
+	}
+	rule.safe = rankSpan(king, rule.kingTo)
+	rule.empty = (rule.safe | rankSpan(rule.rook, rule.rookTo)) &^ (Bitboard(1)<<king | Bitboard(1)<<rule.rook)
+	return rule
+}
+
+// castlingTarget is the destination square a castling move is encoded with
+func (b *Board) castlingTarget(rule castlingRule) int8 {
+	if b.Flags&Chess960 != 0 {
+		return rule.rook
+	}
+	return rule.kingTo
+}
+
+// castlingSide returns 0 (kingside) or 1 (queenside) if a king move from -> to castles, -1 otherwise
+func (b *Board) castlingSide(color int8, from, to int8) int8 {
+	for side := 0; side < 2; side++ {
+		if b.Flags&castlingRights[color][side] == 0 {
+			continue
+		}
+		if b.castlingTarget(b.castlingRule(color, side, from)) == to && from != to {
+			return int8(side)
+		}
+	}
+	return -1
+}
+
+// castlingMask clears the castling rights tied to a square, the king's or a castling rook's
+func (b *Board) castlingMask(sq int8) uint8 {
+	mask := uint8(0xFF)
+	for color := int8(0); color < 2; color++ {
+		for side := 0; side < 2; side++ {
+			right := castlingRights[color][side]
+			if b.Flags&right != 0 && (b.CastlingRooks[color][side] == sq || b.Kings[color]&(Bitboard(1)<<sq) != 0) {
+				mask &^= right
+			}
+		}
+	}
+	return mask
+}
+
+// rankSpan is every square from a to b inclusive, both on the same rank
+func rankSpan(a, b int8) Bitboard {
+	return Between(int(a), int(b)) | Bitboard(1)<<a | Bitboard(1)<<b
+}
+
+// ActiveColor returns the bitboard index (White or Black) of the side to move
+func (b *Board) ActiveColor() int8 {
+	if b.Flags&WhiteToMove != 0 {
+		return White
+	}
+	return Black
+}
+
+// InCheck reports whether the side to move has its king attacked
+func (b *Board) InCheck() bool {
+	color := b.ActiveColor()
+	if b.Kings[color] == 0 {
+		return false
+	}
+	kingSq := bits.TrailingZeros64(uint64(b.Kings[color]))
+	return IsSquareAttacked(kingSq, b, 1-color)
+}
+
+// GenerateLegalMoves lists every legal move for the side to move.
+// Promotions are expanded into one move per promotion code.
+func GenerateLegalMoves(board *Board) []Move {
+	moves := generatePseudoLegalMoves(board, make([]Move, 0, 48))
+	check := newLegalityCheck(board)
+
+	legal := moves[:0]
+	for _, m := range moves {
+		if check.isLegal(board, m) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// HasLegalMoves is a cheaper GenerateLegalMoves(board) != 0 check
+func HasLegalMoves(board *Board) bool {
+	moves := generatePseudoLegalMoves(board, make([]Move, 0, 48))
+	check := newLegalityCheck(board)
+	for _, m := range moves {
+		if check.isLegal(board, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// legalityCheck settles most pseudo-legal moves from the pins alone,
+// only king moves, en passant and moves while in check get played out
+type legalityCheck struct {
+	kingSq  int
+	pinned  Bitboard
+	inCheck bool
+}
+
+func newLegalityCheck(board *Board) legalityCheck {
+	color := board.ActiveColor()
+	if board.Kings[color] == 0 {
+		return legalityCheck{kingSq: -1}
+	}
+	c := legalityCheck{kingSq: bits.TrailingZeros64(uint64(board.Kings[color])), inCheck: board.InCheck()}
+	visitPins(board, color, func(p Pin) { c.pinned |= Bitboard(1) << p.Square })
+	return c
+}
+
+func (c legalityCheck) isLegal(board *Board, m Move) bool {
+	switch {
+	case c.kingSq < 0:
+		return true // variants where the king can be captured
+	case c.inCheck:
+		return leavesKingSafe(board, m)
+	case m.IsDrop():
+		return true // filling a square can't expose the king
+	case int(m.From) == c.kingSq, m.To == board.EnPassantSquare:
+		return leavesKingSafe(board, m)
+	case c.pinned&(Bitboard(1)<<m.From) != 0:
+		return Aligned(c.kingSq, int(m.From), int(m.To))
+	}
+	return true
+}
+
+// leavesKingSafe plays the move and checks the mover's king before taking it back,
+// which takes care of pins, discovered checks and en passant edge cases
+func leavesKingSafe(board *Board, m Move) bool {
+	color := board.ActiveColor()
+	if board.Kings[color] == 0 {
+		return true // variants where the king can be captured
+	}
+	undo := MakeMove(board, m.From, m.To, m.Promotion)
+	kingSq := bits.TrailingZeros64(uint64(board.Kings[color]))
+	safe := !IsSquareAttacked(kingSq, board, 1-color)
+	UnmakeMove(board, undo)
+	return safe
+}
+
+func generatePseudoLegalMoves(board *Board, moves []Move) []Move {
+	color := board.ActiveColor()
+	enemy := 1 - color
+	own := board.Occupied[color]
+	them := board.Occupied[enemy]
+	all := own | them
+	isWhite := color == White
+
+	// Pawns
+	pawns := board.Pawns[color]
+	forward := int8(8)
+	if !isWhite {
+		forward = -8
+	}
+
+	single := SinglePawnPush(pawns, ^all, isWhite)
+	for single != 0 {
+		to := int8(PopLSB(&single))
+		moves = appendPawnMove(moves, to-forward, to)
+	}
+
+	double := DoublePawnPush(pawns, ^all, isWhite)
+	for double != 0 {
+		to := int8(PopLSB(&double))
+		moves = append(moves, Move{From: to - 2*forward, To: to})
+	}
+
+	for bb := pawns; bb != 0; {
+		from := int8(PopLSB(&bb))
+		captures := PawnAttacks(Bitboard(1)<<from, them, isWhite)
+		for captures != 0 {
+			moves = appendPawnMove(moves, from, int8(PopLSB(&captures)))
+		}
+	}
+
+	if board.EnPassantSquare >= 0 {
+		// pawns that attack the en passant square are the ones an enemy pawn there would attack
+		attackers := PawnAttacks(Bitboard(1)<<board.EnPassantSquare, pawns, !isWhite)
+		for attackers != 0 {
+			moves = append(moves, Move{From: int8(PopLSB(&attackers)), To: board.EnPassantSquare})
+		}
+	}
+
+	// Pieces
+	for bb := board.Knights[color]; bb != 0; {
+		from := PopLSB(&bb)
+		moves = appendMoves(moves, from, knightMoves[from]&^own)
+	}
+	for bb := board.Bishops[color] | board.Queens[color]; bb != 0; {
+		from := PopLSB(&bb)
+		moves = appendMoves(moves, from, bishopAttacks(from, all)&^own)
+	}
+	for bb := board.Rooks[color] | board.Queens[color]; bb != 0; {
+		from := PopLSB(&bb)
+		moves = appendMoves(moves, from, rookAttacks(from, all)&^own)
+	}
+	for bb := board.Kings[color]; bb != 0; {
+		from := PopLSB(&bb)
+		moves = appendMoves(moves, from, kingMoves[from]&^own)
+	}
+
+	if board.Flags&DropsAllowed != 0 {
+		moves = appendDrops(board, color, ^all, moves)
+	}
+
+	return appendCastlingMoves(board, color, all, moves)
+}
+
+func appendCastlingMoves(board *Board, color int8, all Bitboard, moves []Move) []Move {
+	if board.Flags&(castlingRights[color][0]|castlingRights[color][1]) == 0 || board.Kings[color] == 0 {
+		return moves
+	}
+	king := int8(bits.TrailingZeros64(uint64(board.Kings[color])))
+
+	for side := 0; side < 2; side++ {
+		if board.Flags&castlingRights[color][side] == 0 {
+			continue
+		}
+		rule := board.castlingRule(color, side, king)
+		if board.Rooks[color]&(Bitboard(1)<<rule.rook) == 0 {
+			continue
+		}
+		if all&rule.empty != 0 {
+			continue
+		}
+		if anySquareAttacked(board, rule.safe, 1-color) {
+			continue
+		}
+		moves = append(moves, Move{From: king, To: board.castlingTarget(rule)})
+	}
+	return moves
+}
+
+func anySquareAttacked(board *Board, squares Bitboard, attackerColor int8) bool {
+	for squares != 0 {
+		if IsSquareAttacked(PopLSB(&squares), board, attackerColor) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendMoves(moves []Move, from int, targets Bitboard) []Move {
+	for targets != 0 {
+		moves = append(moves, Move{From: int8(from), To: int8(PopLSB(&targets))})
+	}
+	return moves
+}
+
+func appendPawnMove(moves []Move, from, to int8) []Move {
+	if to >= 56 || to < 8 {
+		for _, promo := range promotionCodes {
+			moves = append(moves, Move{From: from, To: to, Promotion: promo})
+		}
+		return moves
+	}
+	return append(moves, Move{From: from, To: to})
+}
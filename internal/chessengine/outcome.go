@@ -0,0 +1,173 @@
+package chess
+
+type Result uint8
+
+const (
+	ResultNone Result = iota
+	ResultWhiteWins
+	ResultBlackWins
+	ResultDraw
+)
+
+func (r Result) String() string {
+	switch r {
+	case ResultWhiteWins:
+		return "1-0"
+	case ResultBlackWins:
+		return "0-1"
+	case ResultDraw:
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// WinFor returns the result in which the given color wins
+func WinFor(color int8) Result {
+	if color == White {
+		return ResultWhiteWins
+	}
+	return ResultBlackWins
+}
+
+// Termination says why a game ended
+type Termination uint8
+
+const (
+	TerminationNone Termination = iota
+	TerminationCheckmate
+	TerminationStalemate
+	TerminationThreefoldRepetition
+	TerminationFiftyMoveRule
+	TerminationInsufficientMaterial
+	TerminationAbandoned
+	TerminationKingOfTheHill
+	TerminationThreeChecks
+	TerminationAllPiecesLost
+	TerminationTimeout
+	TerminationTimeoutVsInsufficientMaterial
+	TerminationFivefoldRepetition
+	TerminationSeventyFiveMoveRule
+	TerminationResignation
+	TerminationAgreement
+	TerminationAborted
+)
+
+var terminationNames = map[Termination]string{
+	TerminationNone:                 "none",
+	TerminationCheckmate:            "checkmate",
+	TerminationStalemate:            "stalemate",
+	TerminationThreefoldRepetition:  "threefold repetition",
+	TerminationFiftyMoveRule:        "fifty-move rule",
+	TerminationInsufficientMaterial: "insufficient material",
+	TerminationAbandoned:            "abandoned",
+	TerminationKingOfTheHill:        "king reached the hill",
+	TerminationThreeChecks:          "three checks",
+	TerminationAllPiecesLost:        "all pieces lost",
+	TerminationTimeout:              "time forfeit",
+
+	TerminationTimeoutVsInsufficientMaterial: "timeout vs insufficient material",
+
+	TerminationFivefoldRepetition:  "fivefold repetition",
+	TerminationSeventyFiveMoveRule: "seventy-five-move rule",
+	TerminationResignation:         "resignation",
+	TerminationAgreement:           "draw agreed",
+	TerminationAborted:             "aborted",
+}
+
+func (t Termination) String() string {
+	if name, ok := terminationNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Outcome decides whether the game is over in the current position.
+// history holds every position of the game so far, including the current one.
+// Threefold repetition and the fifty-move rule only end the game when claimed, see ClaimableDraw.
+func Outcome(board *Board, history []Board) (Result, Termination) {
+	if !HasLegalMoves(board) {
+		if board.InCheck() {
+			return WinFor(1 - board.ActiveColor()), TerminationCheckmate
+		}
+		return ResultDraw, TerminationStalemate
+	}
+	if IsInsufficientMaterial(board) {
+		return ResultDraw, TerminationInsufficientMaterial
+	}
+	if reason := automaticDraw(board, history); reason != TerminationNone {
+		return ResultDraw, reason
+	}
+	return ResultNone, TerminationNone
+}
+
+//https://handbook.fide.com/chapter/E012023 (9.6)
+
+// automaticDraw ends the game without a claim after five repetitions or 75 moves without a capture or pawn move
+func automaticDraw(board *Board, history []Board) Termination {
+	if board.HalfmoveClock >= 150 {
+		return TerminationSeventyFiveMoveRule
+	}
+	if RepetitionCount(board, history) >= 5 {
+		return TerminationFivefoldRepetition
+	}
+	return TerminationNone
+}
+
+// ClaimableDraw returns the rule a player may claim a draw by in the current position, TerminationNone if there is none
+func ClaimableDraw(board *Board, history []Board) Termination {
+	if RepetitionCount(board, history) >= 3 {
+		return TerminationThreefoldRepetition
+	}
+	if board.HalfmoveClock >= 100 {
+		return TerminationFiftyMoveRule
+	}
+	return TerminationNone
+}
+
+// RepetitionCount counts how often the current position occurs in history.
+// Only positions since the last capture or pawn move can repeat.
+func RepetitionCount(board *Board, history []Board) int {
+	count := 0
+	for i := len(history) - 1; i >= 0 && i >= len(history)-1-int(board.HalfmoveClock); i-- {
+		if history[i].Hash == board.Hash {
+			count++
+		}
+	}
+	return count
+}
+
+// IsInsufficientMaterial reports positions where neither side can possibly mate:
+// bare kings, a single minor piece, or bishops that all stand on one square color.
+func IsInsufficientMaterial(board *Board) bool {
+	if board.Pockets != [2][5]uint8{} {
+		return false
+	}
+	for color := 0; color < 2; color++ {
+		if board.Pawns[color]|board.Rooks[color]|board.Queens[color] != 0 {
+			return false
+		}
+	}
+
+	knights := board.Knights[White] | board.Knights[Black]
+	bishops := board.Bishops[White] | board.Bishops[Black]
+	if CountBits(knights|bishops) <= 1 {
+		return true
+	}
+
+	const darkSquares Bitboard = 0xAA55AA55AA55AA55
+	return knights == 0 && (bishops&darkSquares == 0 || bishops&^darkSquares == 0)
+}
+
+// HasMatingMaterial reports whether color could still mate at all, used when the opponent's flag falls.
+// A lone king can't, nor can king and one minor piece against a bare king.
+func HasMatingMaterial(board *Board, color int8) bool {
+	if board.Pockets[color] != [5]uint8{} || board.Pawns[color]|board.Rooks[color]|board.Queens[color] != 0 {
+		return true
+	}
+	minors := CountBits(board.Knights[color] | board.Bishops[color])
+	if minors == 0 {
+		return false
+	}
+	opponentBare := board.Occupied[1-color] == board.Kings[1-color]
+	return minors > 1 || !opponentBare
+}
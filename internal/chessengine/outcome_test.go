@@ -0,0 +1,71 @@
+package chess
+
+import "testing"
+
+func TestHasMatingMaterial(t *testing.T) {
+	tests := []struct {
+		fen   string
+		color int8
+		want  bool
+	}{
+		{StartingFEN, White, true},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", White, false},
+		{"4k3/8/8/8/8/8/8/4KN2 w - - 0 1", White, false},
+		{"4k3/8/8/8/8/8/8/4KB2 w - - 0 1", White, false},
+		{"4k3/7p/8/8/8/8/8/4KB2 w - - 0 1", White, true}, // the pawn can block the king in
+		{"4k3/8/8/8/8/8/8/3NKN2 w - - 0 1", White, true},
+		{"4k3/8/8/8/8/8/8/4K2P w - - 0 1", White, true},
+		{"4k3/8/8/8/8/8/8/4K2P w - - 0 1", Black, false},
+		{"4k3/8/8/8/8/8/8/4K3[n] w - - 0 1", Black, true},
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		if got := HasMatingMaterial(&board, tt.color); got != tt.want {
+			t.Errorf("%s color %d: got %v, want %v", tt.fen, tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestDrawRules(t *testing.T) {
+	board := mustParseFEN(t, "4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+	if got := ClaimableDraw(&board, []Board{board}); got != TerminationNone {
+		t.Errorf("99 halfmoves: claimable %v", got)
+	}
+	board.HalfmoveClock = 100
+	if got := ClaimableDraw(&board, []Board{board}); got != TerminationFiftyMoveRule {
+		t.Errorf("100 halfmoves: claimable %v, want fifty-move rule", got)
+	}
+	if result, _ := Outcome(&board, []Board{board}); result != ResultNone {
+		t.Errorf("fifty-move rule ended the game without a claim")
+	}
+	board.HalfmoveClock = 150
+	if result, reason := Outcome(&board, []Board{board}); result != ResultDraw || reason != TerminationSeventyFiveMoveRule {
+		t.Errorf("150 halfmoves: got %v %v, want seventy-five-move draw", result, reason)
+	}
+
+	board = mustParseFEN(t, StartingFEN)
+	history := []Board{board}
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	for repetition := 2; repetition <= 5; repetition++ {
+		for _, uci := range shuffle {
+			m, err := ParseUCIMove(&board, uci)
+			if err != nil {
+				t.Fatal(err)
+			}
+			StandardChess.MakeMove(&board, m)
+			history = append(history, board)
+		}
+		claim := ClaimableDraw(&board, history)
+		result, reason := Outcome(&board, history)
+		switch {
+		case repetition < 3 && claim != TerminationNone:
+			t.Errorf("%d repetitions: claimable %v", repetition, claim)
+		case repetition >= 3 && claim != TerminationThreefoldRepetition:
+			t.Errorf("%d repetitions: claimable %v, want threefold", repetition, claim)
+		case repetition < 5 && result != ResultNone:
+			t.Errorf("%d repetitions ended the game: %v", repetition, reason)
+		case repetition == 5 && (result != ResultDraw || reason != TerminationFivefoldRepetition):
+			t.Errorf("5 repetitions: got %v %v, want fivefold draw", result, reason)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package chess
+
+// Perft counts the leaf nodes of the legal move tree down to depth.
+// Comparing against published counts is the standard way to validate move generation.
+func Perft(board *Board, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := GenerateLegalMoves(board)
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, m := range moves {
+		undo := MakeMove(board, m.From, m.To, m.Promotion)
+		nodes += Perft(board, depth-1)
+		UnmakeMove(board, undo)
+	}
+	return nodes
+}
+
+// Divide runs Perft for every root move separately, which narrows down
+// a wrong node count to the move that causes it
+func Divide(board *Board, depth int) map[Move]uint64 {
+	result := make(map[Move]uint64)
+	if depth < 1 {
+		return result
+	}
+
+	for _, m := range GenerateLegalMoves(board) {
+		undo := MakeMove(board, m.From, m.To, m.Promotion)
+		result[m] = Perft(board, depth-1)
+		UnmakeMove(board, undo)
+	}
+	return result
+}
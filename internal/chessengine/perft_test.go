@@ -0,0 +1,161 @@
+package chess
+
+import "testing"
+
+type perftCase struct {
+	name  string
+	fen   string
+	depth int
+	nodes uint64
+	slow  bool
+}
+
+var perftCases = []perftCase{
+	{"initial d1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 1, 20, false},
+	{"initial d2", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 2, 400, false},
+	{"initial d3", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 3, 8902, false},
+	{"initial d4", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 4, 197281, false},
+	{"initial d5", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 5, 4865609, true},
+
+	{"kiwipete d1", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 1, 48, false},
+	{"kiwipete d2", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 2, 2039, false},
+	{"kiwipete d3", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 3, 97862, false},
+	{"kiwipete d4", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 4, 4085603, true},
+
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 5, 674624, false},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 4, 422333, false},
+	{"position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", 4, 422333, false},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 3, 62379, false},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", 3, 89890, false},
+
+	// en passant
+	{"illegal ep move 1", "3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1", 6, 1134888, true},
+	{"illegal ep move 2", "8/8/4k3/8/2p5/8/B2P2K1/8 w - - 0 1", 6, 1015133, true},
+	{"ep capture checks opponent", "8/8/1k6/2b5/2pP4/8/5K2/8 b - d3 0 1", 6, 1440467, true},
+	{"ep discovered rank pin", "8/8/8/K2pP2r/8/8/8/7k w - d6 0 1", 1, 6, false},
+
+	// castling
+	{"short castling gives check", "5k2/8/8/8/8/8/8/4K2R w K - 0 1", 6, 661072, true},
+	{"long castling gives check", "3k4/8/8/8/8/8/8/R3K3 w Q - 0 1", 6, 803711, true},
+	{"castle rights", "r3k2r/1b4bq/8/8/8/8/7B/R3K2R w KQkq - 0 1", 4, 1274206, true},
+	{"castling prevented", "r3k2r/8/3Q4/8/8/5q2/8/R3K2R b KQkq - 0 1", 4, 1720476, true},
+
+	// promotion
+	{"promote out of check", "2K2r2/4P3/8/8/8/8/8/3k4 w - - 0 1", 6, 3821001, true},
+	{"promote to give check", "4k3/1P6/8/8/8/8/K7/8 w - - 0 1", 6, 217342, false},
+	{"underpromote to check", "8/P1k5/K7/8/8/8/8/8 w - - 0 1", 6, 92683, false},
+
+	// checks and stalemate
+	{"discovered check", "8/8/1P2K3/8/2n5/1q6/8/5k2 b - - 0 1", 5, 1004658, true},
+	{"self stalemate", "K1k5/8/P7/8/8/8/8/8 w - - 0 1", 6, 2217, false},
+	{"stalemate and checkmate", "8/k1P5/8/1K6/8/8/8/8 w - - 0 1", 7, 567584, false},
+	{"double check", "8/8/2k5/5q2/5n2/8/5K2/8 b - - 0 1", 4, 23527, false},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tc := range perftCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.slow && testing.Short() {
+				t.Skip("slow perft skipped in short mode")
+			}
+			board := mustParseFEN(t, tc.fen)
+			if got := Perft(&board, tc.depth); got != tc.nodes {
+				t.Errorf("perft(%d) = %d, want %d", tc.depth, got, tc.nodes)
+				for m, n := range Divide(&board, tc.depth) {
+					t.Logf("  %s: %d", moveToString(m), n)
+				}
+			}
+		})
+	}
+}
+
+func TestDivideSumsToPerft(t *testing.T) {
+	board := mustParseFEN(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+
+	var total uint64
+	divide := Divide(&board, 3)
+	for _, n := range divide {
+		total += n
+	}
+	if len(divide) != 48 {
+		t.Errorf("divide has %d root moves, want 48", len(divide))
+	}
+	if want := Perft(&board, 3); total != want {
+		t.Errorf("divide sums to %d, perft says %d", total, want)
+	}
+}
+
+// walks the tree and compares the incremental Zobrist hash against a full recompute
+func checkHashes(t *testing.T, board *Board, depth int) {
+	if got := ComputeHash(board); got != board.Hash {
+		t.Fatalf("incremental hash %x differs from computed %x", board.Hash, got)
+	}
+	if depth == 0 {
+		return
+	}
+	for _, m := range GenerateLegalMoves(board) {
+		child := *board
+		MakeMove(&child, m.From, m.To, m.Promotion)
+		checkHashes(t, &child, depth-1)
+	}
+}
+
+func TestIncrementalHash(t *testing.T) {
+	for _, tc := range perftCases {
+		board := mustParseFEN(t, tc.fen)
+		checkHashes(t, &board, 3)
+	}
+}
+
+func TestIsMoveLegal(t *testing.T) {
+	// white king in check from the rook on e8, castling through/out of check is not allowed
+	board := mustParseFEN(t, "4r1k1/8/8/8/8/8/8/R3K2R w KQ - 0 1")
+	if IsMoveLegal(&board, 4, 6, PromoteNone) {
+		t.Error("castled out of check")
+	}
+	if !IsMoveLegal(&board, 4, 3, PromoteNone) {
+		t.Error("king can't step out of check")
+	}
+	if IsMoveLegal(&board, 12, 20, PromoteNone) || IsMoveLegal(&board, -1, 20, PromoteNone) {
+		t.Error("accepted a move without a piece")
+	}
+
+	// f1 attacked by the rook on f8
+	board = mustParseFEN(t, "5rk1/8/8/8/8/8/8/R3K2R w KQ - 0 1")
+	if IsMoveLegal(&board, 4, 6, PromoteNone) {
+		t.Error("castled through an attacked square")
+	}
+	if !IsMoveLegal(&board, 4, 2, PromoteNone) {
+		t.Error("queenside castling rejected")
+	}
+}
+
+// every move must be taken back to the exact same board, hash and counters included
+func checkUnmake(t *testing.T, board *Board, depth int) {
+	if depth == 0 {
+		return
+	}
+	for _, m := range GenerateLegalMoves(board) {
+		before := *board
+		undo := MakeMove(board, m.From, m.To, m.Promotion)
+		checkUnmake(t, board, depth-1)
+		UnmakeMove(board, undo)
+		if *board != before {
+			t.Fatalf("unmaking %s from %s left %s", moveToString(m), before.FEN(), board.FEN())
+		}
+	}
+}
+
+func TestMakeUnmake(t *testing.T) {
+	for _, tc := range perftCases {
+		board := mustParseFEN(t, tc.fen)
+		checkUnmake(t, &board, 3)
+	}
+}
+
+func BenchmarkPerftKiwipete(b *testing.B) {
+	board := mustParseFEN(b, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	for i := 0; i < b.N; i++ {
+		Perft(&board, 3)
+	}
+}
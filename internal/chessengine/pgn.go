@@ -0,0 +1,82 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PGNTags holds the seven tag roster plus the extra tags we attach to saved games
+type PGNTags struct {
+	Event       string
+	Site        string
+	Date        string // YYYY.MM.DD, "??" for unknown parts
+	Round       string
+	White       string
+	Black       string
+	Result      Result
+	Mode        string
+	TimeControl string
+	Variant     string // e.g. "Chess960", empty for standard chess
+}
+
+const pgnLineWidth = 80
+
+// WritePGN renders a full PGN game: tag pairs followed by wrapped movetext and the result.
+// Games not starting from the standard position get SetUp/FEN tags.
+func WritePGN(tags PGNTags, start Board, moves []Move) string {
+	var sb strings.Builder
+
+	writeTag := func(name, value string) {
+		if value == "" {
+			value = "?"
+		}
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `"`, `\"`)
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, value)
+	}
+
+	writeTag("Event", tags.Event)
+	writeTag("Site", tags.Site)
+	if tags.Date == "" {
+		tags.Date = "????.??.??"
+	}
+	writeTag("Date", tags.Date)
+	writeTag("Round", tags.Round)
+	writeTag("White", tags.White)
+	writeTag("Black", tags.Black)
+	writeTag("Result", tags.Result.String())
+	if tags.Mode != "" {
+		writeTag("Mode", tags.Mode)
+	}
+	if tags.TimeControl != "" {
+		writeTag("TimeControl", tags.TimeControl)
+	}
+	if tags.Variant != "" {
+		writeTag("Variant", tags.Variant)
+	}
+	if fen := start.FEN(); fen != StartingFEN {
+		writeTag("SetUp", "1")
+		writeTag("FEN", fen)
+	}
+	sb.WriteByte('\n')
+
+	movetext := strings.Fields(start.ToPGN(moves))
+	movetext = append(movetext, tags.Result.String())
+
+	lineLen := 0
+	for _, token := range movetext {
+		if lineLen > 0 && lineLen+1+len(token) > pgnLineWidth {
+			sb.WriteByte('\n')
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			sb.WriteByte(' ')
+			lineLen++
+		}
+		sb.WriteString(token)
+		lineLen += len(token)
+	}
+	sb.WriteByte('\n')
+
+	return sb.String()
+}
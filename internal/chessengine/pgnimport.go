@@ -0,0 +1,468 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PGNMove is one ply of an imported game together with its annotations
+type PGNMove struct {
+	Move       Move
+	SAN        string
+	Comment    string
+	NAGs       []uint8
+	Variations [][]PGNMove // alternatives to this move, played from the position before it
+}
+
+// PGNGame is a parsed and replayed PGN game.
+// BoardHistory starts with Start and has one entry per mainline move.
+type PGNGame struct {
+	Tags         map[string]string
+	Start        Board
+	MainLine     []PGNMove
+	Moves        []Move
+	BoardHistory []Board
+	Result       Result
+}
+
+// PGNError reports the ply (1-based, counted from the start position) and the move that failed
+type PGNError struct {
+	Ply  int
+	Move string
+	Err  error
+}
+
+func (e *PGNError) Error() string {
+	return fmt.Sprintf("pgn: ply %d (%s): %v", e.Ply, e.Move, e.Err)
+}
+
+func (e *PGNError) Unwrap() error {
+	return e.Err
+}
+
+var suffixNAGs = map[string]uint8{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// ParsePGN reads the first game from PGN text
+func ParsePGN(text string) (*PGNGame, error) {
+	games, err := ParseAllPGN(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(games) == 0 {
+		return nil, errors.New("pgn: no game found")
+	}
+	return games[0], nil
+}
+
+// ParseAllPGN reads every game in a PGN database
+func ParseAllPGN(text string) ([]*PGNGame, error) {
+	tokens, err := tokenizePGN(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var games []*PGNGame
+	for len(tokens) > 0 {
+		var game *PGNGame
+		game, tokens, err = parseGame(tokens)
+		if err != nil {
+			return games, err
+		}
+		if game != nil {
+			games = append(games, game)
+		}
+	}
+	return games, nil
+}
+
+type pgnTokenKind uint8
+
+const (
+	tokSymbol pgnTokenKind = iota
+	tokTag
+	tokComment
+	tokNAG
+	tokOpen
+	tokClose
+	tokResult
+)
+
+type pgnToken struct {
+	kind  pgnTokenKind
+	text  string
+	value string // tag value
+}
+
+func tokenizePGN(text string) ([]pgnToken, error) {
+	var tokens []pgnToken
+	lineStart := true
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\n':
+			lineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '%' && lineStart:
+			// escape line
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		lineStart = false
+
+		switch {
+		case c == '[':
+			end := strings.IndexByte(text[i:], ']')
+			if end < 0 {
+				return nil, errors.New("pgn: unterminated tag")
+			}
+			name, value, err := parseTag(text[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, pgnToken{kind: tokTag, text: name, value: value})
+			i += end + 1
+
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, errors.New("pgn: unterminated comment")
+			}
+			tokens = append(tokens, pgnToken{kind: tokComment, text: strings.TrimSpace(text[i+1 : i+end])})
+			i += end + 1
+
+		case c == ';':
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			tokens = append(tokens, pgnToken{kind: tokComment, text: strings.TrimSpace(text[i+1 : i+end])})
+			i += end
+
+		case c == '(':
+			tokens = append(tokens, pgnToken{kind: tokOpen})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, pgnToken{kind: tokClose})
+			i++
+
+		case c == '$':
+			j := i + 1
+			for j < len(text) && text[j] >= '0' && text[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, pgnToken{kind: tokNAG, text: text[i+1 : j]})
+			i = j
+
+		default:
+			j := i
+			for j < len(text) && !strings.ContainsRune(" \t\r\n[]{}();$", rune(text[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("pgn: unexpected character %q", c)
+			}
+			tokens = append(tokens, symbolTokens(text[i:j])...)
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func parseTag(body string) (string, string, error) {
+	body = strings.TrimSpace(body)
+	space := strings.IndexAny(body, " \t")
+	if space < 0 {
+		return "", "", fmt.Errorf("pgn: malformed tag [%s]", body)
+	}
+	name := body[:space]
+	value := strings.TrimSpace(body[space:])
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", "", fmt.Errorf("pgn: malformed tag value in [%s]", body)
+	}
+	value = value[1 : len(value)-1]
+	value = strings.ReplaceAll(value, `\"`, `"`)
+	value = strings.ReplaceAll(value, `\\`, `\`)
+	return name, value, nil
+}
+
+// symbolTokens splits "12.e4!?" style symbols into move numbers, moves, suffixes and results
+func symbolTokens(sym string) []pgnToken {
+	switch sym {
+	case "1-0", "0-1", "1/2-1/2", "*":
+		return []pgnToken{{kind: tokResult, text: sym}}
+	}
+
+	// strip a leading move number ("12." or "12...")
+	digits := 0
+	for digits < len(sym) && sym[digits] >= '0' && sym[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits < len(sym) && sym[digits] == '.' {
+		sym = strings.TrimLeft(sym[digits:], ".")
+	} else if digits == len(sym) {
+		return nil
+	}
+	sym = strings.TrimLeft(sym, ".")
+	if sym == "" {
+		return nil
+	}
+
+	// trailing !/? annotations become NAGs
+	end := len(sym)
+	for end > 0 && (sym[end-1] == '!' || sym[end-1] == '?') {
+		end--
+	}
+	tokens := []pgnToken{{kind: tokSymbol, text: sym[:end]}}
+	if nag, ok := suffixNAGs[sym[end:]]; ok {
+		tokens = append(tokens, pgnToken{kind: tokNAG, text: strconv.Itoa(int(nag))})
+	}
+	return tokens
+}
+
+func parseGame(tokens []pgnToken) (*PGNGame, []pgnToken, error) {
+	game := &PGNGame{Tags: make(map[string]string), Result: ResultNone}
+
+	for len(tokens) > 0 && tokens[0].kind == tokTag {
+		game.Tags[tokens[0].text] = tokens[0].value
+		tokens = tokens[1:]
+	}
+
+	game.Start = NewStartingPosition()
+	if fen, ok := game.Tags["FEN"]; ok {
+		start, err := ParseFEN(fen)
+		if err != nil {
+			return nil, nil, err
+		}
+		game.Start = start
+	}
+	variant := strings.ToLower(game.Tags["Variant"])
+	if strings.Contains(variant, "960") || strings.Contains(variant, "fischer") {
+		game.Start.Flags |= Chess960
+	}
+	if variant == "crazyhouse" {
+		game.Start.Flags |= DropsAllowed | PocketCaptures
+	}
+
+	game.BoardHistory = []Board{game.Start}
+	line, rest, err := parseLine(tokens, game.Start, 1, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens = rest
+
+	game.MainLine = line
+	board := game.Start
+	for _, m := range line {
+		MakeMove(&board, m.Move.From, m.Move.To, m.Move.Promotion)
+		game.Moves = append(game.Moves, m.Move)
+		game.BoardHistory = append(game.BoardHistory, board)
+	}
+
+	if len(tokens) > 0 && tokens[0].kind == tokResult {
+		game.Result = parseResult(tokens[0].text)
+		tokens = tokens[1:]
+	} else if result, ok := game.Tags["Result"]; ok {
+		game.Result = parseResult(result)
+	}
+
+	if len(line) == 0 && len(game.Tags) == 0 {
+		return nil, tokens, nil
+	}
+	return game, tokens, nil
+}
+
+// parseLine reads moves until a result, the closing bracket of a variation or the next game
+func parseLine(tokens []pgnToken, board Board, ply int, depth int) ([]PGNMove, []pgnToken, error) {
+	var line []PGNMove
+	before := board // position before the last move, where variations branch off
+
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		switch tok.kind {
+		case tokTag, tokResult:
+			if depth > 0 {
+				return nil, nil, &PGNError{Ply: ply, Move: tok.text, Err: errors.New("unterminated variation")}
+			}
+			return line, tokens, nil
+
+		case tokClose:
+			if depth == 0 {
+				return nil, nil, &PGNError{Ply: ply, Move: ")", Err: errors.New("unbalanced parenthesis")}
+			}
+			return line, tokens[1:], nil
+
+		case tokOpen:
+			if len(line) == 0 {
+				return nil, nil, &PGNError{Ply: ply, Move: "(", Err: errors.New("variation before any move")}
+			}
+			variation, rest, err := parseLine(tokens[1:], before, ply-1, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			last := &line[len(line)-1]
+			last.Variations = append(last.Variations, variation)
+			tokens = rest
+			continue
+
+		case tokComment:
+			if len(line) > 0 {
+				last := &line[len(line)-1]
+				if last.Comment != "" {
+					last.Comment += " "
+				}
+				last.Comment += tok.text
+			}
+
+		case tokNAG:
+			if len(line) > 0 {
+				if nag, err := strconv.ParseUint(tok.text, 10, 8); err == nil {
+					last := &line[len(line)-1]
+					last.NAGs = append(last.NAGs, uint8(nag))
+				}
+			}
+
+		case tokSymbol:
+			m, err := ParseSAN(&board, tok.text)
+			if err != nil {
+				return nil, nil, &PGNError{Ply: ply, Move: tok.text, Err: err}
+			}
+			line = append(line, PGNMove{Move: m, SAN: MoveToSAN(&board, m)})
+			before = board
+			MakeMove(&board, m.From, m.To, m.Promotion)
+			ply++
+		}
+		tokens = tokens[1:]
+	}
+
+	if depth > 0 {
+		return nil, nil, &PGNError{Ply: ply, Move: "(", Err: errors.New("unterminated variation")}
+	}
+	return line, tokens, nil
+}
+
+func parseResult(s string) Result {
+	switch s {
+	case "1-0":
+		return ResultWhiteWins
+	case "0-1":
+		return ResultBlackWins
+	case "1/2-1/2":
+		return ResultDraw
+	}
+	return ResultNone
+}
+
+// ParseSAN finds the legal move described by a SAN string such as "Nbd7", "exd6", "e8=Q+" or "O-O"
+func ParseSAN(board *Board, san string) (Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	color := board.ActiveColor()
+	legal := GenerateLegalMoves(board)
+
+	if s == "O-O" || s == "0-0" || s == "O-O-O" || s == "0-0-0" {
+		side := int8(0)
+		if len(s) == 5 {
+			side = 1
+		}
+		for _, m := range legal {
+			if GetPieceType(board, m.From, color) == King && board.castlingSide(color, m.From, m.To) == side {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("illegal castling %q", san)
+	}
+
+	// drops: "N@f3", pawns as "@e4" or "P@e4"
+	if at := strings.IndexByte(s, '@'); at >= 0 {
+		piece := Pawn
+		if at == 1 {
+			piece = strings.IndexByte("PNBRQ", s[0])
+		}
+		to, err := ParseSquare(s[at+1:])
+		if at > 1 || piece < 0 || err != nil {
+			return Move{}, fmt.Errorf("malformed drop %q", san)
+		}
+		drop := DropMove(piece, to)
+		for _, m := range legal {
+			if m == drop {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("illegal drop %q", san)
+	}
+
+	piece := Pawn
+	if len(s) > 0 && strings.IndexByte("NBRQK", s[0]) >= 0 {
+		piece = strings.IndexByte("PNBRQK", s[0])
+		s = s[1:]
+	}
+
+	promotion := PromoteNone
+	if eq := strings.IndexByte(s, '='); eq >= 0 {
+		s, promotion = s[:eq], promotionCode(s[eq+1:])
+		if promotion == PromoteNone {
+			return Move{}, fmt.Errorf("invalid promotion in %q", san)
+		}
+	} else if piece == Pawn && len(s) > 0 && strings.IndexByte("NBRQ", s[len(s)-1]) >= 0 {
+		s, promotion = s[:len(s)-1], promotionCode(s[len(s)-1:])
+	}
+
+	if len(s) < 2 {
+		return Move{}, fmt.Errorf("malformed move %q", san)
+	}
+	to, err := ParseSquare(s[len(s)-2:])
+	if err != nil {
+		return Move{}, fmt.Errorf("malformed move %q", san)
+	}
+
+	// whatever is left is the disambiguation, optionally followed by 'x'
+	hint := strings.TrimSuffix(s[:len(s)-2], "x")
+	fromFile, fromRank := int8(-1), int8(-1)
+	for _, c := range hint {
+		switch {
+		case c >= 'a' && c <= 'h':
+			fromFile = int8(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = int8(c - '1')
+		default:
+			return Move{}, fmt.Errorf("malformed move %q", san)
+		}
+	}
+
+	var found []Move
+	for _, m := range legal {
+		if m.To != to || m.Promotion != promotion || GetPieceType(board, m.From, color) != piece {
+			continue
+		}
+		if (fromFile >= 0 && m.From%8 != fromFile) || (fromRank >= 0 && m.From/8 != fromRank) {
+			continue
+		}
+		found = append(found, m)
+	}
+
+	switch len(found) {
+	case 0:
+		return Move{}, fmt.Errorf("illegal move %q", san)
+	case 1:
+		return found[0], nil
+	}
+	return Move{}, fmt.Errorf("ambiguous move %q", san)
+}
+
+func promotionCode(letter string) int8 {
+	for code, l := range promotionLetters {
+		if l == letter {
+			return code
+		}
+	}
+	return PromoteNone
+}
@@ -0,0 +1,139 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+const operaGame = `[Event "Paris"]
+[Site "Paris FRA"]
+[Date "1858.??.??"]
+[Round "?"]
+[White "Paul Morphy"]
+[Black "Duke Karl / Count Isouard"]
+[Result "1-0"]
+
+% escaped line, ignored
+1.e4 e5 2.Nf3 d6 3.d4 Bg4 {This is a weak move already.--Fischer} 4.dxe5 Bxf3
+5.Qxf3 dxe5 6.Bc4 Nf6 7.Qb3 Qe7 8.Nc3 c6 9.Bg5 b5 $2 10.Nxb5! cxb5 11.Bxb5+ Nbd7
+12.O-O-O Rd8 13.Rxd7 Rxd7 14.Rd1 Qe6 (14...Qb4 15.Bxf6 ; rest of line comment
+gxf6 16.Qxb4) 15.Bxd7+ Nxd7 16.Qb8+ Nxb8 17.Rd8# 1-0
+`
+
+func TestParsePGN(t *testing.T) {
+	game, err := ParsePGN(operaGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if game.Tags["White"] != "Paul Morphy" || game.Tags["Black"] != "Duke Karl / Count Isouard" {
+		t.Errorf("tags not parsed: %v", game.Tags)
+	}
+	if game.Result != ResultWhiteWins {
+		t.Errorf("result = %v", game.Result)
+	}
+	if len(game.Moves) != 33 || len(game.BoardHistory) != 34 {
+		t.Fatalf("got %d moves and %d positions", len(game.Moves), len(game.BoardHistory))
+	}
+
+	final := game.BoardHistory[len(game.BoardHistory)-1]
+	if want := "1n1Rkb1r/p4ppp/4q3/4p1B1/4P3/8/PPP2PPP/2K5 b k - 1 17"; final.FEN() != want {
+		t.Errorf("final position %s, want %s", final.FEN(), want)
+	}
+	if result, reason := Outcome(&final, game.BoardHistory); result != ResultWhiteWins || reason != TerminationCheckmate {
+		t.Errorf("final position outcome = %v %v", result, reason)
+	}
+
+	if c := game.MainLine[5].Comment; c != "This is a weak move already.--Fischer" {
+		t.Errorf("comment = %q", c)
+	}
+	if nags := game.MainLine[17].NAGs; len(nags) != 1 || nags[0] != 2 {
+		t.Errorf("$2 on b5 = %v", nags)
+	}
+	if nags := game.MainLine[18].NAGs; len(nags) != 1 || nags[0] != 1 {
+		t.Errorf("! on Nxb5 = %v", nags)
+	}
+
+	variations := game.MainLine[27].Variations
+	if len(variations) != 1 || len(variations[0]) != 4 || variations[0][0].SAN != "Qb4" || variations[0][3].SAN != "Qxb4" {
+		t.Fatalf("variation on 14...Qe6 = %+v", variations)
+	}
+	if variations[0][1].Comment != "rest of line comment" {
+		t.Errorf("variation comment = %q", variations[0][1].Comment)
+	}
+}
+
+func TestPGNRoundTrip(t *testing.T) {
+	game, err := ParsePGN(operaGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := WritePGN(PGNTags{White: "Morphy", Result: game.Result}, game.Start, game.Moves)
+
+	again, err := ParsePGN(written)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, written)
+	}
+	if len(again.Moves) != len(game.Moves) {
+		t.Fatalf("round trip lost moves: %d vs %d", len(again.Moves), len(game.Moves))
+	}
+	for i := range game.Moves {
+		if again.Moves[i] != game.Moves[i] {
+			t.Errorf("ply %d: %v vs %v", i+1, again.Moves[i], game.Moves[i])
+		}
+	}
+}
+
+func TestParsePGNFromFEN(t *testing.T) {
+	game, err := ParsePGN(`[SetUp "1"]
+[FEN "4k3/P7/8/8/8/8/8/4K3 w - - 0 40"]
+
+40. a8=Q+ Kd7 41. Qb7+ *`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves) != 3 || game.Moves[0].Promotion != PromoteQueen || game.Result != ResultNone {
+		t.Errorf("unexpected game: %+v", game.Moves)
+	}
+}
+
+func TestParsePGNErrors(t *testing.T) {
+	_, err := ParsePGN("1. e4 e5 2. Nf3 Ke7 3. Ke3")
+	var pgnErr *PGNError
+	if !errors.As(err, &pgnErr) {
+		t.Fatalf("expected PGNError, got %v", err)
+	}
+	if pgnErr.Ply != 5 || pgnErr.Move != "Ke3" {
+		t.Errorf("error at ply %d move %q", pgnErr.Ply, pgnErr.Move)
+	}
+
+	for _, bad := range []string{"1. e4 (e5", "1. e4 e5)", "{unterminated", "1. Nbd2"} {
+		if _, err := ParsePGN(bad); err == nil {
+			t.Errorf("ParsePGN(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseAllPGN(t *testing.T) {
+	games, err := ParseAllPGN("[Event \"a\"]\n\n1. e4 1-0\n\n[Event \"b\"]\n\n1. d4 d5 0-1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].Tags["Event"] != "a" || len(games[1].Moves) != 2 || games[1].Result != ResultBlackWins {
+		t.Errorf("unexpected games: %+v", games)
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	board := mustParseFEN(t, "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1")
+	if m, err := ParseSAN(&board, "R1a3"); err != nil || m.From != 0 {
+		t.Errorf("R1a3 = %v %v", m, err)
+	}
+	if _, err := ParseSAN(&board, "Ra3"); err == nil {
+		t.Error("ambiguous Ra3 accepted")
+	}
+	board = mustParseFEN(t, "8/4P3/8/8/k7/8/8/4K3 w - - 0 1")
+	if m, err := ParseSAN(&board, "e8N"); err != nil || m.Promotion != PromoteKnight {
+		t.Errorf("e8N = %v %v", m, err)
+	}
+}
@@ -0,0 +1,166 @@
+package chess
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// Polyglot .bin books are sorted 16 byte big endian entries: key, move, weight, learn
+const polyglotEntrySize = 16
+
+// polyglot promotion field: 1 knight, 2 bishop, 3 rook, 4 queen
+var polyglotPromotions = [5]int8{PromoteNone, PromoteKnight, PromoteBishop, PromoteRook, PromoteQueen}
+
+type BookEntry struct {
+	Move   Move
+	Weight uint16
+	Learn  uint32
+}
+
+type polyglotEntry struct {
+	key    uint64
+	move   uint16
+	weight uint16
+	learn  uint32
+}
+
+type PolyglotBook struct {
+	entries []polyglotEntry
+}
+
+// PolyglotKey hashes a position with Polyglot's keys, they are unrelated to Board.Hash
+func PolyglotKey(b *Board) uint64 {
+	var key uint64
+	for color := int8(0); color < 2; color++ {
+		for piece := Pawn; piece <= King; piece++ {
+			kind := 2*piece + int(color) // black pawn, white pawn, black knight, ...
+			for bb := *b.pieceBB(color, piece); bb != 0; {
+				key ^= polyglotRandom[64*kind+PopLSB(&bb)]
+			}
+		}
+	}
+
+	for i, right := range [4]uint8{WK, WQ, BK, BQ} {
+		if b.Flags&right != 0 {
+			key ^= polyglotRandom[768+i]
+		}
+	}
+
+	// the en passant file only counts when a pawn can actually take
+	if b.EnPassantSquare >= 0 {
+		color := b.ActiveColor()
+		if PawnAttacks(Bitboard(1)<<b.EnPassantSquare, b.Pawns[color], color == Black) != 0 {
+			key ^= polyglotRandom[772+int(b.EnPassantSquare)%8]
+		}
+	}
+
+	if b.Flags&WhiteToMove != 0 {
+		key ^= polyglotRandom[780]
+	}
+	return key
+}
+
+func LoadPolyglotBook(path string) (*PolyglotBook, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadPolyglotBook(f)
+}
+
+func ReadPolyglotBook(r io.Reader) (*PolyglotBook, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%polyglotEntrySize != 0 {
+		return nil, fmt.Errorf("polyglot book size %d isn't a multiple of %d", len(data), polyglotEntrySize)
+	}
+
+	book := &PolyglotBook{entries: make([]polyglotEntry, len(data)/polyglotEntrySize)}
+	for i := range book.entries {
+		e := data[i*polyglotEntrySize:]
+		book.entries[i] = polyglotEntry{
+			key:    binary.BigEndian.Uint64(e),
+			move:   binary.BigEndian.Uint16(e[8:]),
+			weight: binary.BigEndian.Uint16(e[10:]),
+			learn:  binary.BigEndian.Uint32(e[12:]),
+		}
+	}
+	// books are supposed to be sorted already, but lookups depend on it
+	sort.SliceStable(book.entries, func(i, j int) bool { return book.entries[i].key < book.entries[j].key })
+	return book, nil
+}
+
+func (bk *PolyglotBook) Len() int {
+	return len(bk.entries)
+}
+
+// Moves lists the book moves for the position, entries that aren't legal here are skipped
+func (bk *PolyglotBook) Moves(board *Board) []BookEntry {
+	key := PolyglotKey(board)
+	i := sort.Search(len(bk.entries), func(i int) bool { return bk.entries[i].key >= key })
+
+	var found []BookEntry
+	legal := GenerateLegalMoves(board)
+	for ; i < len(bk.entries) && bk.entries[i].key == key; i++ {
+		m, ok := decodePolyglotMove(board, bk.entries[i].move)
+		if !ok {
+			continue
+		}
+		for _, l := range legal {
+			if l == m {
+				found = append(found, BookEntry{Move: m, Weight: bk.entries[i].weight, Learn: bk.entries[i].learn})
+				break
+			}
+		}
+	}
+	return found
+}
+
+// WeightedMove picks a book move with probability proportional to its weight
+func (bk *PolyglotBook) WeightedMove(board *Board) (Move, bool) {
+	entries := bk.Moves(board)
+	total := 0
+	for _, e := range entries {
+		total += int(e.Weight)
+	}
+	if total == 0 {
+		return Move{}, false
+	}
+
+	n := rand.Intn(total)
+	for _, e := range entries {
+		if n -= int(e.Weight); n < 0 {
+			return e.Move, true
+		}
+	}
+	return Move{}, false
+}
+
+func decodePolyglotMove(board *Board, raw uint16) (Move, bool) {
+	to := int8(raw & 0x3F)
+	from := int8(raw >> 6 & 0x3F)
+	promotion := int(raw >> 12 & 0x7)
+	if promotion >= len(polyglotPromotions) {
+		return Move{}, false
+	}
+	m := Move{From: from, To: to, Promotion: polyglotPromotions[promotion]}
+
+	// polyglot writes castling as king takes rook (e1h1), which is already our Chess960 encoding
+	color := board.ActiveColor()
+	if board.Flags&Chess960 == 0 && board.Kings[color]&(Bitboard(1)<<from) != 0 {
+		switch {
+		case to == from+3 && board.Rooks[color]&(Bitboard(1)<<to) != 0:
+			m.To = from + 2
+		case to == from-4 && board.Rooks[color]&(Bitboard(1)<<to) != 0:
+			m.To = from - 2
+		}
+	}
+	return m, true
+}
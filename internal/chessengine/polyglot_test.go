@@ -0,0 +1,111 @@
+package chess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// test vectors from the Polyglot book format description
+func TestPolyglotKey(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want uint64
+	}{
+		{StartingFEN, 0x463b96181691fc9c},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 0x823c9b50fd114196},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", 0x0756b94461c50fb0},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", 0x662fafb965db29d4},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", 0x22a48b5a8e47ff78},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 0 3", 0x652a607ca3f242c1},
+		{"rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 0 4", 0x00fdd303c946bdd9},
+		{"rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3", 0x3c8123ea7b067637},
+		{"rnbqkbnr/p1pppppp/8/8/P6P/R1p5/1P1PPPP1/1NBQKBNR b Kkq - 0 4", 0x5c3f9b829b279560},
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		if got := PolyglotKey(&board); got != tt.want {
+			t.Errorf("%s: got %016x, want %016x", tt.fen, got, tt.want)
+		}
+	}
+}
+
+func TestPolyglotKeysDistinct(t *testing.T) {
+	seen := make(map[uint64]int, len(polyglotRandom))
+	for i, k := range polyglotRandom {
+		if j, ok := seen[k]; ok {
+			t.Errorf("keys %d and %d are both %016x", j, i, k)
+		}
+		seen[k] = i
+	}
+}
+
+func polyglotBookBytes(entries ...polyglotEntry) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		binary.Write(&buf, binary.BigEndian, e.key)
+		binary.Write(&buf, binary.BigEndian, e.move)
+		binary.Write(&buf, binary.BigEndian, e.weight)
+		binary.Write(&buf, binary.BigEndian, e.learn)
+	}
+	return buf.Bytes()
+}
+
+func polyglotMove(from, to int, promotion int) uint16 {
+	return uint16(promotion<<12 | from<<6 | to)
+}
+
+func TestPolyglotBookMoves(t *testing.T) {
+	start := NewStartingPosition()
+	castling := mustParseFEN(t, "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1")
+	promotion := mustParseFEN(t, "8/P6k/8/8/8/8/8/K7 w - - 0 1")
+
+	data := polyglotBookBytes(
+		// deliberately unsorted
+		polyglotEntry{key: PolyglotKey(&castling), move: polyglotMove(4, 7, 0), weight: 5},    // e1h1, O-O
+		polyglotEntry{key: PolyglotKey(&start), move: polyglotMove(12, 28, 0), weight: 30},    // e4
+		polyglotEntry{key: PolyglotKey(&start), move: polyglotMove(11, 27, 0), weight: 10},    // d4
+		polyglotEntry{key: PolyglotKey(&start), move: polyglotMove(12, 36, 0), weight: 50},    // e5, not legal
+		polyglotEntry{key: PolyglotKey(&castling), move: polyglotMove(4, 0, 0), weight: 1},    // e1a1, O-O-O
+		polyglotEntry{key: PolyglotKey(&promotion), move: polyglotMove(48, 56, 4), weight: 1}, // a8=Q
+	)
+	book, err := ReadPolyglotBook(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Len() != 6 {
+		t.Fatalf("got %d entries, want 6", book.Len())
+	}
+
+	moves := book.Moves(&start)
+	if len(moves) != 2 || moves[0].Move != (Move{From: 12, To: 28}) || moves[0].Weight != 30 || moves[1].Move != (Move{From: 11, To: 27}) {
+		t.Errorf("start position: got %+v", moves)
+	}
+
+	moves = book.Moves(&castling)
+	if len(moves) != 2 || moves[0].Move != (Move{From: 4, To: 6}) || moves[1].Move != (Move{From: 4, To: 2}) {
+		t.Errorf("castling: got %+v", moves)
+	}
+
+	moves = book.Moves(&promotion)
+	if len(moves) != 1 || moves[0].Move != (Move{From: 48, To: 56, Promotion: PromoteQueen}) {
+		t.Errorf("promotion: got %+v", moves)
+	}
+
+	empty := mustParseFEN(t, "4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if _, ok := book.WeightedMove(&empty); ok {
+		t.Error("got a book move for a position that isn't in the book")
+	}
+	for i := 0; i < 20; i++ {
+		m, ok := book.WeightedMove(&start)
+		if !ok || (m != Move{From: 12, To: 28} && m != Move{From: 11, To: 27}) {
+			t.Fatalf("weighted move %+v, %v", m, ok)
+		}
+	}
+}
+
+func TestReadPolyglotBookBadSize(t *testing.T) {
+	if _, err := ReadPolyglotBook(bytes.NewReader(make([]byte, 20))); err == nil {
+		t.Error("expected an error for a truncated book")
+	}
+}
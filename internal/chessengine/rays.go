@@ -0,0 +1,72 @@
+package chess
+
+//https://www.chessprogramming.org/Square_Attacked_By#Pure_Calculation
+
+// ray directions, clockwise from north (towards rank 8)
+const (
+	North = iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+)
+
+var rayDeltas = [8][2]int{ // file, rank
+	North:     {0, 1},
+	NorthEast: {1, 1},
+	East:      {1, 0},
+	SouthEast: {1, -1},
+	South:     {0, -1},
+	SouthWest: {-1, -1},
+	West:      {-1, 0},
+	NorthWest: {-1, 1},
+}
+
+var rays [8][64]Bitboard          // [direction][square] every square from square to the edge, square excluded
+var betweenTable [64][64]Bitboard // squares strictly between two aligned squares
+var lineTable [64][64]Bitboard    // whole line through two aligned squares, edge to edge
+
+func initRays() {
+	for sq := 0; sq < 64; sq++ {
+		for dir, d := range rayDeltas {
+			var walked Bitboard
+			for f, r := sq%8+d[0], sq/8+d[1]; f >= 0 && f < 8 && r >= 0 && r < 8; f, r = f+d[0], r+d[1] {
+				to := r*8 + f
+				rays[dir][sq] |= Bitboard(1) << to
+				betweenTable[sq][to] = walked
+				walked |= Bitboard(1) << to
+			}
+		}
+	}
+	for a := 0; a < 64; a++ {
+		for dir := 0; dir < 4; dir++ {
+			line := rays[dir][a] | rays[dir+4][a] | Bitboard(1)<<a
+			for targets := rays[dir][a] | rays[dir+4][a]; targets != 0; {
+				lineTable[a][PopLSB(&targets)] = line
+			}
+		}
+	}
+}
+
+// Ray is every square from sq towards the board edge in direction dir, sq excluded
+func Ray(dir, sq int) Bitboard {
+	return rays[dir][sq]
+}
+
+// Between is the squares strictly between a and b, empty unless they share a rank, file or diagonal
+func Between(a, b int) Bitboard {
+	return betweenTable[a][b]
+}
+
+// Line is the full rank, file or diagonal through a and b, empty if they aren't aligned
+func Line(a, b int) Bitboard {
+	return lineTable[a][b]
+}
+
+// Aligned reports whether the three squares lie on one line
+func Aligned(a, b, c int) bool {
+	return Line(a, b)&(Bitboard(1)<<c) != 0
+}
@@ -0,0 +1,101 @@
+package chess
+
+import "testing"
+
+// direction from a to b, or -1 if they don't share a line
+func directionBetween(a, b int) int {
+	df, dr := b%8-a%8, b/8-a/8
+	if a == b || (df != 0 && dr != 0 && abs(df) != abs(dr)) {
+		return -1
+	}
+	sign := func(x int) int {
+		switch {
+		case x > 0:
+			return 1
+		case x < 0:
+			return -1
+		}
+		return 0
+	}
+	for dir, d := range rayDeltas {
+		if d[0] == sign(df) && d[1] == sign(dr) {
+			return dir
+		}
+	}
+	return -1
+}
+
+func TestRaysMatchSlidingAttacks(t *testing.T) {
+	deltas := [8]int{North: 8, NorthEast: 9, East: 1, SouthEast: -7, South: -8, SouthWest: -9, West: -1, NorthWest: 7}
+	for sq := 0; sq < 64; sq++ {
+		for dir, d := range deltas {
+			if got, want := Ray(dir, sq), slidingAttacks(sq, 0, []int{d}); got != want {
+				t.Errorf("Ray(%d, %d) = %x, want %x", dir, sq, got, want)
+			}
+		}
+	}
+}
+
+func TestBetweenAndLine(t *testing.T) {
+	for a := 0; a < 64; a++ {
+		for b := 0; b < 64; b++ {
+			dir := directionBetween(a, b)
+
+			var between, line Bitboard
+			if dir >= 0 {
+				d := rayDeltas[dir]
+				for f, r := a%8+d[0], a/8+d[1]; r*8+f != b; f, r = f+d[0], r+d[1] {
+					between |= Bitboard(1) << (r*8 + f)
+				}
+				opposite := (dir + 4) % 8
+				line = Ray(dir, a) | Ray(opposite, a) | Bitboard(1)<<a
+			}
+
+			if got := Between(a, b); got != between {
+				t.Errorf("Between(%d, %d) = %x, want %x", a, b, got, between)
+			}
+			if got := Line(a, b); got != line {
+				t.Errorf("Line(%d, %d) = %x, want %x", a, b, got, line)
+			}
+			if Between(a, b) != Between(b, a) || Line(a, b) != Line(b, a) {
+				t.Errorf("tables aren't symmetric for %d, %d", a, b)
+			}
+			if dir >= 0 && (Line(a, b)&(Bitboard(1)<<a) == 0 || Line(a, b)&(Bitboard(1)<<b) == 0 || Between(a, b)&^Line(a, b) != 0) {
+				t.Errorf("Line(%d, %d) doesn't hold both squares and everything between", a, b)
+			}
+		}
+	}
+}
+
+func TestAligned(t *testing.T) {
+	tests := []struct {
+		a, b, c string
+		want    bool
+	}{
+		{"a1", "h8", "d4", true},
+		{"a1", "d4", "h8", true}, // c doesn't have to be between
+		{"a1", "h8", "d5", false},
+		{"e1", "e8", "e4", true},
+		{"e1", "a5", "c3", true},
+		{"e1", "a5", "d3", false},
+		{"a1", "b3", "c5", false}, // knight jumps aren't lines
+	}
+	for _, tt := range tests {
+		a, b, c := int(mustSquare(tt.a)), int(mustSquare(tt.b)), int(mustSquare(tt.c))
+		if got := Aligned(a, b, c); got != tt.want {
+			t.Errorf("Aligned(%s, %s, %s) = %v, want %v", tt.a, tt.b, tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestRankSpan(t *testing.T) {
+	if got, want := rankSpan(4, 6), sqBB("e1", "f1", "g1"); got != want {
+		t.Errorf("rankSpan(e1, g1) = %x, want %x", got, want)
+	}
+	if got, want := rankSpan(58, 60), sqBB("c8", "d8", "e8"); got != want {
+		t.Errorf("rankSpan(c8, e8) = %x, want %x", got, want)
+	}
+	if got, want := rankSpan(5, 5), sqBB("f1"); got != want {
+		t.Errorf("rankSpan(f1, f1) = %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+var sanPieces = [6]string{"", "N", "B", "R", "Q", "K"} // indexed by piece type
+
+var promotionLetters = map[int8]string{
+	PromoteRook:   "R",
+	PromoteKnight: "N",
+	PromoteBishop: "B",
+	PromoteQueen:  "Q",
+	PromoteKing:   "K",
+}
+
+// MoveToSAN writes a legal move in Standard Algebraic Notation,
+// board is the position before the move is played
+func MoveToSAN(board *Board, m Move) string {
+	color := board.ActiveColor()
+	piece := GetPieceType(board, m.From, color)
+
+	var sb strings.Builder
+	switch {
+	case m.IsDrop():
+		sb.WriteString(sanPieces[m.DropPiece()] + "@" + squareToString(m.To))
+
+	case piece == King && board.castlingSide(color, m.From, m.To) >= 0:
+		if board.castlingSide(color, m.From, m.To) == 0 {
+			sb.WriteString("O-O")
+		} else {
+			sb.WriteString("O-O-O")
+		}
+
+	case piece == Pawn:
+		if m.From%8 != m.To%8 {
+			sb.WriteByte(squareToString(m.From)[0])
+			sb.WriteByte('x')
+		}
+		sb.WriteString(squareToString(m.To))
+		if letter, ok := promotionLetters[m.Promotion]; ok {
+			sb.WriteString("=" + letter)
+		}
+
+	default:
+		sb.WriteString(sanPieces[piece])
+		sb.WriteString(disambiguation(board, m, piece))
+		if GetPieceType(board, m.To, 1-color) >= 0 {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(squareToString(m.To))
+	}
+
+	after := *board
+	MakeMove(&after, m.From, m.To, m.Promotion)
+	if after.InCheck() {
+		if HasLegalMoves(&after) {
+			sb.WriteByte('+')
+		} else {
+			sb.WriteByte('#')
+		}
+	}
+	return sb.String()
+}
+
+// disambiguation returns the file, rank or full square of the origin
+// when another piece of the same type could also reach the target
+func disambiguation(board *Board, m Move, piece int) string {
+	color := board.ActiveColor()
+	ambiguous, sameFile, sameRank := false, false, false
+
+	for _, other := range GenerateLegalMoves(board) {
+		if other.To != m.To || other.From == m.From || GetPieceType(board, other.From, color) != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From%8 == m.From%8 {
+			sameFile = true
+		}
+		if other.From/8 == m.From/8 {
+			sameRank = true
+		}
+	}
+
+	from := squareToString(m.From)
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	}
+	return from
+}
+
+// MovesToSAN converts a sequence of moves played from start into SAN
+func MovesToSAN(start Board, moves []Move) []string {
+	board := start
+	sans := make([]string, 0, len(moves))
+	for _, m := range moves {
+		sans = append(sans, MoveToSAN(&board, m))
+		MakeMove(&board, m.From, m.To, m.Promotion)
+	}
+	return sans
+}
+
+// MoveToUCI writes a move in UCI long algebraic notation ("e2e4", "e7e8q", "N@f3")
+func MoveToUCI(m Move) string {
+	return moveToString(m)
+}
+
+// ParseUCIMove reads a UCI move and checks it is legal on board
+func ParseUCIMove(board *Board, s string) (Move, error) {
+	var m Move
+	switch {
+	case len(s) == 4 && s[1] == '@':
+		piece := strings.IndexByte("PNBRQ", s[0])
+		to, err := ParseSquare(s[2:])
+		if piece < 0 || err != nil {
+			return m, fmt.Errorf("invalid uci move %q", s)
+		}
+		m = DropMove(piece, to)
+	case len(s) == 4 || len(s) == 5:
+		from, err1 := ParseSquare(s[:2])
+		to, err2 := ParseSquare(s[2:4])
+		if err1 != nil || err2 != nil {
+			return m, fmt.Errorf("invalid uci move %q", s)
+		}
+		m = Move{From: from, To: to, Promotion: PromoteNone}
+		if len(s) == 5 {
+			if m.Promotion = promotionCode(strings.ToUpper(s[4:])); m.Promotion == PromoteNone {
+				return m, fmt.Errorf("invalid promotion in %q", s)
+			}
+		}
+	default:
+		return m, fmt.Errorf("invalid uci move %q", s)
+	}
+
+	for _, legal := range GenerateLegalMoves(board) {
+		if legal == m {
+			return m, nil
+		}
+	}
+	return m, fmt.Errorf("illegal move %q", s)
+}
@@ -0,0 +1,83 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMoveToSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move Move
+		want string
+	}{
+		{StartingFEN, Move{From: 12, To: 28}, "e4"},
+		{StartingFEN, Move{From: 6, To: 21}, "Nf3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", Move{From: 4, To: 6}, "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", Move{From: 60, To: 58}, "O-O-O"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", Move{From: 28, To: 35}, "exd5"},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", Move{From: 36, To: 45}, "exf6"},
+		{"8/4P3/8/8/k7/8/8/4K3 w - - 0 1", Move{From: 52, To: 60, Promotion: PromoteQueen}, "e8=Q+"},
+		{"8/4P3/8/8/k7/8/8/4K3 w - - 0 1", Move{From: 52, To: 60, Promotion: PromoteKnight}, "e8=N"},
+		// knights on b1 and f1 can both reach d2: file disambiguation
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", Move{From: 1, To: 11}, "Nbd2"},
+		// rooks on a1 and a5 share a file: rank disambiguation
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", Move{From: 0, To: 16}, "R1a3"},
+		// queens on a1, a3 and c1 all reach b2: full square
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", Move{From: 0, To: 9}, "Qa1b2"},
+		// pinned knight doesn't count for disambiguation
+		{"4k3/8/8/8/8/8/4r3/2N1KN2 w - - 0 1", Move{From: 5, To: 20}, "Ne3"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2", Move{From: 59, To: 31}, "Qh4#"},
+		{"4k3/8/8/8/8/8/8/4K2r w - - 0 1", Move{From: 4, To: 7}, "Kxh1"},
+	}
+	for _, tc := range tests {
+		board := mustParseFEN(t, tc.fen)
+		if got := MoveToSAN(&board, tc.move); got != tc.want {
+			t.Errorf("%s %v: got %s, want %s", tc.fen, tc.move, got, tc.want)
+		}
+	}
+}
+
+func TestWritePGN(t *testing.T) {
+	start := NewStartingPosition()
+	// scholar's mate
+	moves := []Move{{From: 12, To: 28}, {From: 52, To: 36}, {From: 5, To: 26}, {From: 57, To: 42}, {From: 3, To: 39}, {From: 62, To: 45}, {From: 39, To: 53}}
+	pgn := WritePGN(PGNTags{Event: "Test", White: "1", Black: "2", Result: ResultWhiteWins, Mode: "Classic", TimeControl: "-"}, start, moves)
+
+	for _, want := range []string{
+		`[Event "Test"]`, `[Site "?"]`, `[Date "????.??.??"]`, `[Result "1-0"]`, `[Mode "Classic"]`, `[TimeControl "-"]`,
+		"1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# 1-0",
+	} {
+		if !strings.Contains(pgn, want) {
+			t.Errorf("pgn is missing %q:\n%s", want, pgn)
+		}
+	}
+	if strings.Contains(pgn, "[FEN") {
+		t.Errorf("standard start shouldn't get a FEN tag:\n%s", pgn)
+	}
+
+	custom := mustParseFEN(t, "4k3/8/8/8/8/8/8/4K2R b K - 0 30")
+	pgn = WritePGN(PGNTags{Result: ResultNone}, custom, []Move{{From: 60, To: 59}, {From: 4, To: 6}})
+	if !strings.Contains(pgn, `[FEN "4k3/8/8/8/8/8/8/4K2R b K - 0 30"]`) || !strings.Contains(pgn, "30... Kd8 31. O-O *") {
+		t.Errorf("unexpected custom start pgn:\n%s", pgn)
+	}
+}
+
+func TestUCIMoves(t *testing.T) {
+	board := mustParseFEN(t, "r3k3/1P6/8/8/8/8/8/4K2R w K - 0 1")
+	for _, s := range []string{"b7a8q", "b7b8n", "e1g1", "h1h8"} {
+		m, err := ParseUCIMove(&board, s)
+		if err != nil {
+			t.Errorf("ParseUCIMove(%s): %v", s, err)
+			continue
+		}
+		if got := MoveToUCI(m); got != s {
+			t.Errorf("round trip %s -> %s", s, got)
+		}
+	}
+	for _, s := range []string{"b7a8", "e1e3", "e1c1", "z9a1", "b7b8x"} {
+		if _, err := ParseUCIMove(&board, s); err == nil {
+			t.Errorf("ParseUCIMove(%s) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package chess
+
+import "time"
+
+//https://www.chessprogramming.org/Alpha-Beta
+//https://www.chessprogramming.org/Transposition_Table
+
+const (
+	MateScore      = 30000
+	infinity       = 32000
+	maxSearchDepth = 64
+	maxPly         = 128
+)
+
+type SearchLimits struct {
+	Depth    int           // iterative deepening stops here, 0 means maxSearchDepth
+	MoveTime time.Duration // 0 means no time limit
+}
+
+type SearchResult struct {
+	Move  Move
+	Score int // centipawns for the side to move, mates are MateScore minus the distance in plies
+	Depth int // last fully searched depth
+	Nodes uint64
+}
+
+type ttBound uint8
+
+const (
+	boundExact ttBound = iota
+	boundLower         // fail high, score is at least this
+	boundUpper         // fail low, score is at most this
+)
+
+type ttEntry struct {
+	key   uint64
+	move  Move
+	score int16
+	depth int8
+	bound ttBound
+}
+
+// Searcher keeps its transposition table between searches, so a bot reuses what it learned last move.
+// Not safe for concurrent use.
+type Searcher struct {
+	tt       []ttEntry
+	killers  [maxPly][2]Move
+	path     []uint64 // hashes from the game start down to the current search node, for repetitions
+	nodes    uint64
+	deadline time.Time
+	stopped  bool
+	depth    int  // iteration being searched
+	rootBest Move // best root move of the current iteration
+}
+
+// NewSearcher allocates a transposition table of roughly sizeMB megabytes
+func NewSearcher(sizeMB int) *Searcher {
+	entries := 1
+	for entries*2*24 <= sizeMB<<20 {
+		entries *= 2
+	}
+	return &Searcher{tt: make([]ttEntry, entries)}
+}
+
+// Search finds the best move for the side to move. history holds the game's positions
+// (the current one last) so the search can see repetitions.
+func (s *Searcher) Search(board *Board, history []Board, limits SearchLimits) SearchResult {
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > maxSearchDepth {
+		maxDepth = maxSearchDepth
+	}
+	s.deadline = time.Time{}
+	if limits.MoveTime > 0 {
+		s.deadline = time.Now().Add(limits.MoveTime)
+	}
+	s.nodes, s.stopped = 0, false
+	s.killers = [maxPly][2]Move{}
+	s.path = s.path[:0]
+	for _, h := range history {
+		s.path = append(s.path, h.Hash)
+	}
+	if len(s.path) > 0 && s.path[len(s.path)-1] == board.Hash {
+		s.path = s.path[:len(s.path)-1] // negamax pushes the current position itself
+	}
+
+	var result SearchResult
+	root := *board
+	for depth := 1; depth <= maxDepth; depth++ {
+		s.depth = depth
+		score := s.negamax(&root, depth, 0, -infinity, infinity)
+		if s.stopped {
+			break // an unfinished iteration can't be trusted
+		}
+		result.Move, result.Score, result.Depth = s.rootBest, score, depth
+		if score > MateScore-maxPly || score < -MateScore+maxPly {
+			break // a forced mate won't get any shorter
+		}
+	}
+	result.Nodes = s.nodes
+	return result
+}
+
+func (s *Searcher) negamax(board *Board, depth, ply int, alpha, beta int) int {
+	s.nodes++
+	// the first iteration always finishes so there is a move to play
+	if s.nodes&2047 == 0 && s.depth > 1 && !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.stopped = true
+	}
+	if s.stopped && ply > 0 {
+		return 0
+	}
+
+	if ply > 0 && (board.HalfmoveClock >= 100 || s.isRepetition(board) || IsInsufficientMaterial(board)) {
+		return 0
+	}
+
+	var ttMove Move
+	if entry := s.probe(board.Hash); entry != nil {
+		ttMove = entry.move
+		if ply > 0 && int(entry.depth) >= depth {
+			score := scoreFromTT(int(entry.score), ply)
+			switch {
+			case entry.bound == boundExact,
+				entry.bound == boundLower && score >= beta,
+				entry.bound == boundUpper && score <= alpha:
+				return score
+			}
+		}
+	}
+
+	inCheck := board.InCheck()
+	if inCheck {
+		depth++ // don't stop the search in the middle of a check sequence
+	}
+	if depth <= 0 || ply >= maxPly-1 {
+		return s.quiescence(board, ply, alpha, beta)
+	}
+
+	moves := GenerateLegalMoves(board)
+	if len(moves) == 0 {
+		if inCheck {
+			return -MateScore + ply
+		}
+		return 0
+	}
+	scores := s.orderScores(board, moves, ttMove, ply)
+
+	s.path = append(s.path, board.Hash)
+	defer func() { s.path = s.path[:len(s.path)-1] }()
+
+	bestScore, bestMove, bound := -infinity, moves[0], boundUpper
+	for i := range moves {
+		pickNext(moves, scores, i)
+		m := moves[i]
+
+		undo := MakeMove(board, m.From, m.To, m.Promotion)
+		score := -s.negamax(board, depth-1, ply+1, -beta, -alpha)
+		UnmakeMove(board, undo)
+		if s.stopped {
+			return 0
+		}
+
+		if score > bestScore {
+			bestScore, bestMove = score, m
+			if ply == 0 {
+				s.rootBest = m
+			}
+		}
+		if score > alpha {
+			alpha, bound = score, boundExact
+		}
+		if alpha >= beta {
+			bound = boundLower
+			if undo.CapturedPiece < 0 && m != s.killers[ply][0] {
+				s.killers[ply][1], s.killers[ply][0] = s.killers[ply][0], m
+			}
+			break
+		}
+	}
+
+	s.store(board.Hash, bestMove, scoreToTT(bestScore, ply), depth, bound)
+	return bestScore
+}
+
+// quiescence only follows captures and promotions so the evaluation isn't taken in the middle of an exchange
+func (s *Searcher) quiescence(board *Board, ply int, alpha, beta int) int {
+	s.nodes++
+	if board.InCheck() {
+		// no standing pat while in check, every evasion has to be looked at
+		moves := GenerateLegalMoves(board)
+		if len(moves) == 0 {
+			return -MateScore + ply
+		}
+		if ply >= maxPly-1 {
+			return Evaluate(board)
+		}
+		return s.searchTactical(board, moves, ply, alpha, beta, -infinity)
+	}
+
+	standPat := Evaluate(board)
+	if standPat >= beta || ply >= maxPly-1 {
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	moves := GenerateLegalMoves(board)
+	tactical := moves[:0]
+	for _, m := range moves {
+		if m.Promotion == PromoteQueen || board.Occupied[1-board.ActiveColor()]&(Bitboard(1)<<m.To) != 0 {
+			tactical = append(tactical, m)
+		}
+	}
+	return s.searchTactical(board, tactical, ply, alpha, beta, standPat)
+}
+
+func (s *Searcher) searchTactical(board *Board, moves []Move, ply int, alpha, beta, best int) int {
+	scores := s.orderScores(board, moves, Move{}, ply)
+	for i := range moves {
+		pickNext(moves, scores, i)
+		m := moves[i]
+		undo := MakeMove(board, m.From, m.To, m.Promotion)
+		score := -s.quiescence(board, ply+1, -beta, -alpha)
+		UnmakeMove(board, undo)
+
+		if score > best {
+			best = score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// orderScores rates moves for searching: hash move, captures by MVV-LVA, promotions, killers, the rest
+func (s *Searcher) orderScores(board *Board, moves []Move, ttMove Move, ply int) []int {
+	color := board.ActiveColor()
+	scores := make([]int, len(moves))
+	for i, m := range moves {
+		switch {
+		case m == ttMove:
+			scores[i] = 1_000_000
+		case GetPieceType(board, m.To, 1-color) >= 0:
+			victim := GetPieceType(board, m.To, 1-color)
+			attacker := GetPieceType(board, m.From, color)
+			scores[i] = 100_000 + 10*pieceValues[victim] - attacker
+		case m.Promotion == PromoteQueen:
+			scores[i] = 90_000
+		case ply < maxPly && m == s.killers[ply][0]:
+			scores[i] = 80_000
+		case ply < maxPly && m == s.killers[ply][1]:
+			scores[i] = 79_000
+		}
+	}
+	return scores
+}
+
+// pickNext moves the best scored of the remaining moves to position i
+func pickNext(moves []Move, scores []int, i int) {
+	best := i
+	for j := i + 1; j < len(moves); j++ {
+		if scores[j] > scores[best] {
+			best = j
+		}
+	}
+	moves[i], moves[best] = moves[best], moves[i]
+	scores[i], scores[best] = scores[best], scores[i]
+}
+
+func (s *Searcher) isRepetition(board *Board) bool {
+	for i := len(s.path) - 2; i >= 0 && i >= len(s.path)-int(board.HalfmoveClock); i -= 2 {
+		if s.path[i] == board.Hash {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Searcher) probe(hash uint64) *ttEntry {
+	entry := &s.tt[hash&uint64(len(s.tt)-1)]
+	if entry.key != hash {
+		return nil
+	}
+	return entry
+}
+
+func (s *Searcher) store(hash uint64, m Move, score, depth int, bound ttBound) {
+	entry := &s.tt[hash&uint64(len(s.tt)-1)]
+	if entry.key == hash && int(entry.depth) > depth && bound != boundExact {
+		return // keep the deeper result for this position
+	}
+	*entry = ttEntry{key: hash, move: m, score: int16(score), depth: int8(depth), bound: bound}
+}
+
+// mate scores are stored relative to the node so they stay correct at other plies
+func scoreToTT(score, ply int) int {
+	switch {
+	case score > MateScore-maxPly:
+		return score + ply
+	case score < -MateScore+maxPly:
+		return score - ply
+	}
+	return score
+}
+
+func scoreFromTT(score, ply int) int {
+	switch {
+	case score > MateScore-maxPly:
+		return score - ply
+	case score < -MateScore+maxPly:
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,91 @@
+package chess
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchFindsMate(t *testing.T) {
+	cases := []struct {
+		name, fen, best string
+		depth           int
+		mateIn          int
+	}{
+		{"back rank", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", 2, 1},
+		{"smothered", "6rk/6pp/8/6N1/8/8/8/6K1 w - - 0 1", "g5f7", 2, 1},
+		{"mate in two", "r1b2k1r/ppp1bppp/8/1B1Q4/5q2/2P5/PPP2PPP/R3R1K1 w - - 1 1", "d5d8", 4, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			board := mustParseFEN(t, tc.fen)
+			result := NewSearcher(1).Search(&board, nil, SearchLimits{Depth: tc.depth})
+			if got := moveToString(result.Move); got != tc.best {
+				t.Errorf("best move %s, want %s", got, tc.best)
+			}
+			if want := MateScore - (2*tc.mateIn - 1); result.Score != want {
+				t.Errorf("score %d, want %d", result.Score, want)
+			}
+		})
+	}
+}
+
+func TestSearchWinsMaterial(t *testing.T) {
+	// a queen hanging to a pawn gets taken
+	board := mustParseFEN(t, "4k3/8/8/3q4/4P3/8/8/4K3 w - - 0 1")
+	result := NewSearcher(1).Search(&board, nil, SearchLimits{Depth: 2})
+	if got := moveToString(result.Move); got != "e4d5" {
+		t.Errorf("best %s, want e4d5", got)
+	}
+
+	// knight fork on c7 wins the rook
+	board = mustParseFEN(t, "r3k3/8/8/1N6/8/8/P7/4K3 w - - 0 1")
+	result = NewSearcher(1).Search(&board, nil, SearchLimits{Depth: 3})
+	if got := moveToString(result.Move); got != "b5c7" || result.Score < 200 {
+		t.Errorf("best %s with score %d, want the c7 fork", got, result.Score)
+	}
+}
+
+func TestSearchAvoidsStalemate(t *testing.T) {
+	// Qb6 or Qc7 stalemate, a winning side has to keep the game going
+	board := mustParseFEN(t, "k7/8/2K5/8/8/8/8/1Q6 w - - 0 1")
+	result := NewSearcher(1).Search(&board, nil, SearchLimits{Depth: 4})
+	after := board
+	MakeMove(&after, result.Move.From, result.Move.To, result.Move.Promotion)
+	if !after.InCheck() && !HasLegalMoves(&after) {
+		t.Errorf("%s stalemates", moveToString(result.Move))
+	}
+	if result.Score < MateScore-maxPly {
+		t.Errorf("expected a forced mate, score %d", result.Score)
+	}
+}
+
+func TestSearchRespectsMoveTime(t *testing.T) {
+	board := mustParseFEN(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	start := time.Now()
+	result := NewSearcher(4).Search(&board, nil, SearchLimits{MoveTime: 200 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("search took %s", elapsed)
+	}
+	if !IsMoveLegal(&board, result.Move.From, result.Move.To, result.Move.Promotion) {
+		t.Errorf("illegal move %s", moveToString(result.Move))
+	}
+	if result.Depth < 2 {
+		t.Errorf("only reached depth %d", result.Depth)
+	}
+}
+
+func TestSearchLeavesBoardUntouched(t *testing.T) {
+	board := NewStartingPosition()
+	before := board
+	NewSearcher(1).Search(&board, []Board{board}, SearchLimits{Depth: 3})
+	if board != before {
+		t.Error("search modified the board")
+	}
+}
+
+func BenchmarkSearchDepth5(b *testing.B) {
+	board := mustParseFEN(b, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	for i := 0; i < b.N; i++ {
+		NewSearcher(16).Search(&board, nil, SearchLimits{Depth: 5})
+	}
+}
@@ -0,0 +1,38 @@
+package chess
+
+// MoveEvent is what a Tracer gets for every move played in a game
+type MoveEvent struct {
+	Move     Move
+	Color    int8
+	Piece    int
+	Captured int // -1 when nothing was captured
+	Hash     uint64
+}
+
+// Tracer receives engine events for debugging. The engine stays silent without one.
+type Tracer interface {
+	TraceMove(e MoveEvent)
+}
+
+var tracer Tracer
+
+// SetTracer installs (or with nil removes) the engine tracer.
+// It isn't synchronized, call it before games start.
+func SetTracer(t Tracer) {
+	tracer = t
+}
+
+// TraceMove reports a move played in a game, board is the position after it.
+// MakeMove doesn't call it, so search, perft and legality probes stay quiet.
+func TraceMove(board *Board, undo Undo) {
+	if tracer == nil {
+		return
+	}
+	tracer.TraceMove(MoveEvent{
+		Move:     undo.Move,
+		Color:    1 - board.ActiveColor(),
+		Piece:    int(undo.MovedPiece),
+		Captured: int(undo.CapturedPiece),
+		Hash:     board.Hash,
+	})
+}
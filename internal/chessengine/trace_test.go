@@ -0,0 +1,32 @@
+package chess
+
+import "testing"
+
+type recordingTracer struct {
+	events []MoveEvent
+}
+
+func (r *recordingTracer) TraceMove(e MoveEvent) {
+	r.events = append(r.events, e)
+}
+
+func TestTracer(t *testing.T) {
+	rec := &recordingTracer{}
+	SetTracer(rec)
+	defer SetTracer(nil)
+
+	board := mustParseFEN(t, "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2")
+	undo := MakeMove(&board, 28, 35, PromoteNone) // exd5
+	if len(rec.events) != 0 {
+		t.Fatalf("MakeMove traced %d events, only played moves should be", len(rec.events))
+	}
+	TraceMove(&board, undo)
+
+	if len(rec.events) != 1 {
+		t.Fatalf("got %d events", len(rec.events))
+	}
+	e := rec.events[0]
+	if e.Move != (Move{From: 28, To: 35}) || e.Color != White || e.Piece != Pawn || e.Captured != Pawn || e.Hash != board.Hash {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
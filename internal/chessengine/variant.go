@@ -0,0 +1,195 @@
+package chess
+
+import "math/rand"
+
+// Variant bundles the rules a game is played by, so sessions don't have to know
+// which flavour of chess they are running
+type Variant interface {
+	Name() string
+	StartingPosition() Board
+	LegalMoves(board *Board, history []Board) []Move
+	MakeMove(board *Board, m Move) Undo
+	// Outcome decides whether the game is over, history includes the current position
+	Outcome(board *Board, history []Board) (Result, Termination)
+}
+
+var (
+	StandardChess Variant = standard{}
+	FischerRandom Variant = fischerRandom{}
+	KingOfTheHill Variant = kingOfTheHill{}
+	ThreeCheck    Variant = threeCheck{}
+	Antichess     Variant = antichess{}
+	Crazyhouse    Variant = crazyhouse{}
+	Bughouse      Variant = bughouse{}
+)
+
+// IsVariantMoveLegal validates a move coming from a client against the variant's rules
+func IsVariantMoveLegal(v Variant, board *Board, history []Board, m Move) bool {
+	for _, legal := range v.LegalMoves(board, history) {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+// === Standard ===
+
+type standard struct{}
+
+func (standard) Name() string { return "Standard" }
+
+func (standard) StartingPosition() Board { return NewStartingPosition() }
+
+func (standard) LegalMoves(board *Board, history []Board) []Move {
+	return GenerateLegalMoves(board)
+}
+
+func (standard) MakeMove(board *Board, m Move) Undo {
+	return MakeMove(board, m.From, m.To, m.Promotion)
+}
+
+func (standard) Outcome(board *Board, history []Board) (Result, Termination) {
+	return Outcome(board, history)
+}
+
+// === Chess960 ===
+
+type fischerRandom struct{ standard }
+
+func (fischerRandom) Name() string { return "Chess960" }
+
+func (fischerRandom) StartingPosition() Board {
+	board, _ := NewChess960Position(rand.Intn(960)) // index is always in range
+	return board
+}
+
+// === King of the Hill ===
+
+//https://lichess.org/variant/kingOfTheHill
+
+const hillSquares Bitboard = 0x0000001818000000 // d4, e4, d5, e5
+
+type kingOfTheHill struct{ standard }
+
+func (kingOfTheHill) Name() string { return "King of the Hill" }
+
+func (kingOfTheHill) Outcome(board *Board, history []Board) (Result, Termination) {
+	mover := 1 - board.ActiveColor()
+	if board.Kings[mover]&hillSquares != 0 {
+		return WinFor(mover), TerminationKingOfTheHill
+	}
+	result, reason := Outcome(board, history)
+	if reason == TerminationInsufficientMaterial {
+		return ResultNone, TerminationNone // a bare king can still walk to the hill
+	}
+	return result, reason
+}
+
+// === Three-Check ===
+
+type threeCheck struct{ standard }
+
+func (threeCheck) Name() string { return "Three-check" }
+
+func (threeCheck) Outcome(board *Board, history []Board) (Result, Termination) {
+	checks := ChecksGiven(history)
+	for color := int8(0); color < 2; color++ {
+		if checks[color] >= 3 {
+			return WinFor(color), TerminationThreeChecks
+		}
+	}
+	return Outcome(board, history)
+}
+
+// ChecksGiven counts the checks each color has delivered over the game, indexed by color
+func ChecksGiven(history []Board) [2]int {
+	var checks [2]int
+	for i := 1; i < len(history); i++ {
+		if history[i].InCheck() {
+			checks[1-history[i].ActiveColor()]++
+		}
+	}
+	return checks
+}
+
+// === Antichess ===
+
+//https://lichess.org/variant/antichess
+
+const antichessFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+
+type antichess struct{ standard }
+
+func (antichess) Name() string { return "Antichess" }
+
+func (antichess) StartingPosition() Board {
+	board, _ := ParseFEN(antichessFEN)
+	return board
+}
+
+// LegalMoves ignores check entirely, kings are ordinary pieces and pawns may promote to one.
+// Capturing is compulsory.
+func (antichess) LegalMoves(board *Board, history []Board) []Move {
+	moves := generatePseudoLegalMoves(board, make([]Move, 0, 48))
+	for _, m := range moves {
+		if m.Promotion == PromoteQueen {
+			moves = append(moves, Move{From: m.From, To: m.To, Promotion: PromoteKing})
+		}
+	}
+
+	enemy := board.Occupied[1-board.ActiveColor()]
+	captures := moves[:0:0]
+	for _, m := range moves {
+		if enemy&(Bitboard(1)<<m.To) != 0 || (m.To == board.EnPassantSquare && board.Pawns[board.ActiveColor()]&(Bitboard(1)<<m.From) != 0) {
+			captures = append(captures, m)
+		}
+	}
+	if len(captures) > 0 {
+		return captures
+	}
+	return moves
+}
+
+// Outcome: whoever runs out of pieces or moves first wins
+func (v antichess) Outcome(board *Board, history []Board) (Result, Termination) {
+	color := board.ActiveColor()
+	if board.Occupied[color] == 0 {
+		return WinFor(color), TerminationAllPiecesLost
+	}
+	if len(v.LegalMoves(board, history)) == 0 {
+		return WinFor(color), TerminationStalemate
+	}
+	if reason := automaticDraw(board, history); reason != TerminationNone {
+		return ResultDraw, reason
+	}
+	return ResultNone, TerminationNone
+}
+
+// === Crazyhouse ===
+
+//https://lichess.org/variant/crazyhouse
+
+type crazyhouse struct{ standard }
+
+func (crazyhouse) Name() string { return "Crazyhouse" }
+
+func (crazyhouse) StartingPosition() Board {
+	board := NewStartingPosition()
+	board.Flags |= DropsAllowed | PocketCaptures
+	return board
+}
+
+// === Bughouse ===
+
+// bughouse is crazyhouse where captures land in the partner's pocket on the other board,
+// moving them across is up to whoever runs the two games
+type bughouse struct{ standard }
+
+func (bughouse) Name() string { return "Bughouse" }
+
+func (bughouse) StartingPosition() Board {
+	board := NewStartingPosition()
+	board.Flags |= DropsAllowed
+	return board
+}
@@ -0,0 +1,100 @@
+package chess
+
+import "testing"
+
+// playVariant plays UCI-style moves through the variant, returning the final board and history
+func playVariant(t *testing.T, v Variant, start Board, moves ...string) (Board, []Board) {
+	t.Helper()
+	board := start
+	history := []Board{board}
+	for _, s := range moves {
+		from, _ := ParseSquare(s[:2])
+		to, _ := ParseSquare(s[2:4])
+		m := Move{From: from, To: to, Promotion: PromoteNone}
+		if len(s) == 5 {
+			m.Promotion = promotionCode(string(s[4] - ('a' - 'A')))
+		}
+		if !IsVariantMoveLegal(v, &board, history, m) {
+			t.Fatalf("%s: %s is not legal in %s", v.Name(), s, board.FEN())
+		}
+		v.MakeMove(&board, m)
+		history = append(history, board)
+	}
+	return board, history
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	start := mustParseFEN(t, "4k3/8/8/8/8/4K3/8/8 w - - 0 1")
+	board, history := playVariant(t, KingOfTheHill, start)
+	if result, _ := KingOfTheHill.Outcome(&board, history); result != ResultNone {
+		t.Fatalf("bare kings should play on, got %s", result)
+	}
+
+	board, history = playVariant(t, KingOfTheHill, start, "e3e4")
+	if result, reason := KingOfTheHill.Outcome(&board, history); result != ResultWhiteWins || reason != TerminationKingOfTheHill {
+		t.Errorf("king on e4: %s %s", result, reason)
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	start := ThreeCheck.StartingPosition()
+	board, history := playVariant(t, ThreeCheck, start,
+		"e2e4", "e7e5", "f1c4", "d7d6", "c4f7", "e8f7", "d1h5", "g7g6")
+	if checks := ChecksGiven(history); checks[White] != 2 || checks[Black] != 0 {
+		t.Fatalf("checks = %v", checks)
+	}
+	if result, _ := ThreeCheck.Outcome(&board, history); result != ResultNone {
+		t.Fatalf("game over after two checks: %s", result)
+	}
+
+	board, history = playVariant(t, ThreeCheck, start,
+		"e2e4", "e7e5", "f1c4", "d7d6", "c4f7", "e8f7", "d1h5", "g7g6", "h5g6")
+	if result, reason := ThreeCheck.Outcome(&board, history); result != ResultWhiteWins || reason != TerminationThreeChecks {
+		t.Errorf("third check: %s %s", result, reason)
+	}
+}
+
+func TestAntichessPerft(t *testing.T) {
+	want := []uint64{20, 400, 8067, 153299}
+	board := Antichess.StartingPosition()
+	var perft func(depth int) uint64
+	perft = func(depth int) uint64 {
+		if depth == 0 {
+			return 1
+		}
+		var nodes uint64
+		for _, m := range Antichess.LegalMoves(&board, nil) {
+			undo := Antichess.MakeMove(&board, m)
+			nodes += perft(depth - 1)
+			UnmakeMove(&board, undo)
+		}
+		return nodes
+	}
+	for i, nodes := range want {
+		if got := perft(i + 1); got != nodes {
+			t.Errorf("antichess perft(%d) = %d, want %d", i+1, got, nodes)
+		}
+	}
+}
+
+func TestAntichessRules(t *testing.T) {
+	board, history := playVariant(t, Antichess, Antichess.StartingPosition(), "e2e3", "b7b5")
+	if moves := Antichess.LegalMoves(&board, history); len(moves) != 1 || moveToString(moves[0]) != "f1b5" {
+		t.Errorf("capture should be forced, got %v", moves)
+	}
+
+	promo := mustParseFEN(t, "7k/P7/8/8/8/8/8/K7 w - - 0 1")
+	if moves := Antichess.LegalMoves(&promo, nil); len(moves) != 8 {
+		t.Errorf("expected 5 promotions and 3 king moves, got %d", len(moves))
+	}
+	board, history = playVariant(t, Antichess, promo, "a7a8k")
+	if CountBits(board.Kings[White]) != 2 {
+		t.Error("pawn should have promoted to a king")
+	}
+
+	bare := mustParseFEN(t, "8/8/8/8/8/8/1k6/K7 w - - 0 1")
+	board, history = playVariant(t, Antichess, bare, "a1b2")
+	if result, reason := Antichess.Outcome(&board, history); result != ResultBlackWins || reason != TerminationAllPiecesLost {
+		t.Errorf("losing every piece should win: %s %s", result, reason)
+	}
+}
@@ -14,8 +14,10 @@ type Client struct {
 	QueuedInModes    map[uint16]bool
 	Mu               sync.Mutex
 	disconnected     bool // Track if client is already being disconnected
+	Bot              *Bot // set for the computer opponent, which has no connections
 }
 
+// lock order: clientsMu before Client.Mu, and nothing calls into the GameKeeper while holding clientsMu
 var (
 	clients   = make(map[uint32]*Client)
 	clientsMu sync.RWMutex
@@ -73,34 +75,68 @@ func (c *Client) IsQueuedInMode(mode uint16) bool {
 	return c.QueuedInModes[mode]
 }
 
-func GetClientOrCreate(userID uint32) *Client {
+// GetClientOrCreate returns userID's client, reattached is set when it's the one their running games kept waiting
+func GetClientOrCreate(userID uint32) (client *Client, reattached bool) {
+	// a player whose game is waiting for them gets their old client back, that's the one the game knows
+	waiting := waitingPlayer(userID)
+
 	clientsMu.Lock()
 	defer clientsMu.Unlock()
 
-	if client, ok := clients[userID]; ok {
+	if existing, ok := clients[userID]; ok {
 		// Check if client is disconnected, if so create a new one
-		client.Mu.Lock()
-		disconnected := client.disconnected
-		client.Mu.Unlock()
+		existing.Mu.Lock()
+		disconnected := existing.disconnected
+		existing.Mu.Unlock()
 
 		if disconnected {
-			logger.Log.Info().Uint32("clientId", client.UserID).Msg("Client is diconnected, making new one, and deleting old")
+			logger.Log.Info().Uint32("clientId", existing.UserID).Msg("Client is diconnected, making new one, and deleting old")
 			// Remove the old disconnected client
 			delete(clients, userID)
 		} else {
-			return client
+			return existing, false
 		}
 	}
 
+	if waiting != nil {
+		waiting.Mu.Lock()
+		waiting.disconnected = false
+		waiting.Conns = make(map[uint64]net.Conn)
+		waiting.QueuedInModes = make(map[uint16]bool)
+		waiting.Mu.Unlock()
+		logger.Log.Info().Uint32("clientId", userID).Msg("Client reattached to running game")
+		clients[userID] = waiting
+		return waiting, true
+	}
+
 	// Create new Client
-	client := &Client{
+	client = &Client{
 		UserID:        userID,
 		Conns:         make(map[uint64]net.Conn),
 		QueuedInModes: make(map[uint16]bool),
 	}
 	logger.Log.Info().Uint32("clientId", client.UserID).Msg("Client created")
 	clients[userID] = client
-	return client
+	return client, false
+}
+
+// waitingPlayer is the client a running game holds for userID, nil if they aren't playing
+func waitingPlayer(userID uint32) *Client {
+	for _, game := range GetGameKeeper().GamesOf(userID) {
+		for _, p := range game.Players {
+			if p.UserID == userID && p.Bot == nil {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// ResumeGames tells the client's running games it's connected, each answers with a full snapshot
+func (c *Client) ResumeGames() {
+	for _, game := range GetGameKeeper().GamesOf(c.UserID) {
+		game.notifyPresence(c)
+	}
 }
 
 func GetClient(userID uint32) (*Client, bool) {
@@ -123,14 +159,18 @@ func GetClient(userID uint32) (*Client, bool) {
 	return client, true
 }
 
-func RemoveClient(userID uint32) {
+// RemoveClient drops client once its last connection is gone. It's a no-op if the user
+// has reconnected in the meantime, even when they got the same client back.
+func RemoveClient(client *Client) {
 	clientsMu.Lock()
 	defer clientsMu.Unlock()
 
-	if client, ok := clients[userID]; ok {
-		// Mark as disconnected first
+	if clients[client.UserID] == client {
 		client.Mu.Lock()
-		client.disconnected = true
+		if !client.disconnected {
+			client.Mu.Unlock()
+			return
+		}
 
 		// Close all connections
 		for connID, conn := range client.Conns {
@@ -140,7 +180,7 @@ func RemoveClient(userID uint32) {
 		client.Conns = make(map[uint64]net.Conn) // Clear the map
 		client.Mu.Unlock()
 
-		delete(clients, userID)
+		delete(clients, client.UserID)
 		logger.Log.Info().Uint32("clientId", client.UserID).Msg("Client removed")
 	}
 }
@@ -180,7 +220,7 @@ func (c *Client) RemoveConn(connID uint64) int {
 				c.disconnected = true // Mark as disconnected before handling
 				c.Mu.Unlock()         // Unlock before calling handleDisconnect to avoid deadlock
 				c.handleDisconnect()
-				RemoveClient(c.UserID)
+				RemoveClient(c)
 				c.Mu.Lock() // Re-lock for defer unlock
 			}
 		} else {
@@ -208,6 +248,11 @@ func (c *Client) handleDisconnect() {
 		}(m, i)
 	}
 
+	// running games start the reconnect grace period
+	for _, game := range GetGameKeeper().GamesOf(c.UserID) {
+		game.notifyPresence(c)
+	}
+
 	// Optional: wait for all matchmaker removals to complete
 	// wg.Wait()
 }
@@ -221,6 +266,14 @@ func (c *Client) ConnCount() int {
 	return len(c.Conns)
 }
 
+// IsConnected reports whether the player is still around, bots always are
+func (c *Client) IsConnected() bool {
+	if c.Bot != nil {
+		return true
+	}
+	return c.ConnCount() > 0 && !c.IsDisconnected()
+}
+
 // Helper method to check if client is disconnected
 func (c *Client) IsDisconnected() bool {
 	c.Mu.Lock()
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"strconv"
+	"time"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+//https://en.wikipedia.org/wiki/Time_control
+
+type DelayKind uint8
+
+const (
+	DelayNone      DelayKind = iota
+	DelayBronstein           // the time used is given back after the move, up to Delay
+	DelaySimple              // the clock waits Delay before it starts running
+)
+
+// TimeControl is the clock setting of a game, a zero Base means no clock
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration // Fischer increment, added after every move
+	Delay     time.Duration
+	DelayKind DelayKind
+}
+
+func (tc TimeControl) Unlimited() bool {
+	return tc.Base <= 0
+}
+
+// String is the PGN TimeControl tag value, "600+5" for ten minutes plus five seconds.
+// PGN has no notation for delays, they're appended as "d2" (simple) or "b2" (Bronstein) seconds.
+func (tc TimeControl) String() string {
+	if tc.Unlimited() {
+		return "-"
+	}
+	s := strconv.Itoa(int(tc.Base.Seconds()))
+	if tc.Increment > 0 {
+		s += "+" + strconv.Itoa(int(tc.Increment.Seconds()))
+	}
+	if tc.Delay > 0 {
+		switch tc.DelayKind {
+		case DelaySimple:
+			s += "d" + strconv.Itoa(int(tc.Delay.Seconds()))
+		case DelayBronstein:
+			s += "b" + strconv.Itoa(int(tc.Delay.Seconds()))
+		}
+	}
+	return s
+}
+
+// Clock keeps both players' remaining time, indexed by chess.White/chess.Black.
+// Only the side to move's clock runs.
+type Clock struct {
+	TimeControl
+	Remaining [2]time.Duration // as of the start of the current turn
+	running   int8             // color whose clock runs, -1 when stopped
+	turnStart time.Time
+}
+
+func NewClock(tc TimeControl) *Clock {
+	return &Clock{
+		TimeControl: tc,
+		Remaining:   [2]time.Duration{tc.Base, tc.Base},
+		running:     -1,
+	}
+}
+
+// Start runs color's clock from now
+func (c *Clock) Start(color int8, now time.Time) {
+	c.running = color
+	c.turnStart = now
+}
+
+func (c *Clock) Stop(now time.Time) {
+	if c.running >= 0 {
+		c.Remaining[c.running] = c.RemainingAt(c.running, now)
+		c.running = -1
+	}
+}
+
+// used is how much of the running side's time a turn of this length costs so far
+func (c *Clock) used(elapsed time.Duration) time.Duration {
+	switch c.DelayKind {
+	case DelaySimple:
+		return max(0, elapsed-c.Delay)
+	}
+	return elapsed
+}
+
+// RemainingAt is color's time left at now, it can go negative once the flag has fallen
+func (c *Clock) RemainingAt(color int8, now time.Time) time.Duration {
+	if color != c.running {
+		return c.Remaining[color]
+	}
+	return c.Remaining[color] - c.used(now.Sub(c.turnStart))
+}
+
+// Flagged reports whether the running side has run out of time at now
+func (c *Clock) Flagged(now time.Time) bool {
+	return c.running >= 0 && c.RemainingAt(c.running, now) <= 0
+}
+
+// FlagDeadline is when the running side's flag falls if it doesn't move
+func (c *Clock) FlagDeadline() time.Time {
+	deadline := c.turnStart.Add(c.Remaining[c.running])
+	if c.DelayKind == DelaySimple {
+		deadline = deadline.Add(c.Delay)
+	}
+	return deadline
+}
+
+// Press ends the running side's turn and starts the opponent's clock.
+// It reports false, leaving the clock alone, if the flag fell before the move.
+func (c *Clock) Press(now time.Time) bool {
+	if c.running < 0 {
+		return true
+	}
+	if c.Flagged(now) {
+		return false
+	}
+	color := c.running
+	elapsed := now.Sub(c.turnStart)
+	c.Remaining[color] -= c.used(elapsed)
+	if c.DelayKind == DelayBronstein {
+		c.Remaining[color] += min(elapsed, c.Delay)
+	}
+	c.Remaining[color] += c.Increment
+	c.Start(1-color, now)
+	return true
+}
+
+// Millis is both remaining times in milliseconds at now, white first
+func (c *Clock) Millis(now time.Time) [2]uint32 {
+	var ms [2]uint32
+	for i, color := range []int8{chess.White, chess.Black} {
+		ms[i] = uint32(max(0, c.RemainingAt(color, now).Milliseconds()))
+	}
+	return ms
+}
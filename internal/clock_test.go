@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+var t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestTimeControlString(t *testing.T) {
+	tests := []struct {
+		tc   TimeControl
+		want string
+	}{
+		{TimeControl{}, "-"},
+		{TimeControl{Base: 10 * time.Minute}, "600"},
+		{TimeControl{Base: 10 * time.Minute, Increment: 5 * time.Second}, "600+5"},
+		{TimeControl{Base: 3 * time.Minute, Delay: 2 * time.Second, DelayKind: DelaySimple}, "180d2"},
+		{TimeControl{Base: 5 * time.Minute, Increment: time.Second, Delay: 3 * time.Second, DelayKind: DelayBronstein}, "300+1b3"},
+	}
+	for _, tt := range tests {
+		if got := tt.tc.String(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.tc, got, tt.want)
+		}
+	}
+	if got := ModeBughouse.TimeControl().String(); got != "180d2" {
+		t.Errorf("bughouse time control %q, want the delay in it", got)
+	}
+}
+
+func TestClockIncrement(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute, Increment: 2 * time.Second})
+	c.Start(chess.White, t0)
+
+	if !c.Press(t0.Add(10 * time.Second)) {
+		t.Fatal("white flagged after 10s of 60s")
+	}
+	if got := c.Remaining[chess.White]; got != 52*time.Second {
+		t.Errorf("white has %v after a 10s move with +2s, want 52s", got)
+	}
+	if got := c.RemainingAt(chess.Black, t0.Add(15*time.Second)); got != 55*time.Second {
+		t.Errorf("black has %v 5s into their turn, want 55s", got)
+	}
+	if got := c.RemainingAt(chess.White, t0.Add(15*time.Second)); got != 52*time.Second {
+		t.Errorf("white's clock ran during black's turn: %v", got)
+	}
+}
+
+func TestClockBronsteinRefundIsCapped(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute, Delay: 3 * time.Second, DelayKind: DelayBronstein})
+	c.Start(chess.White, t0)
+
+	// a move within the delay costs nothing
+	now := t0.Add(2 * time.Second)
+	c.Press(now)
+	if got := c.Remaining[chess.White]; got != time.Minute {
+		t.Errorf("white has %v after a 2s move with 3s delay, want 1m", got)
+	}
+
+	// a longer one gets only the delay back
+	now = now.Add(5 * time.Second)
+	c.Press(now)
+	if got := c.Remaining[chess.Black]; got != 58*time.Second {
+		t.Errorf("black has %v after a 5s move with 3s delay, want 58s", got)
+	}
+
+	// the refund comes after the move, the flag deadline doesn't include it
+	if got, want := c.FlagDeadline(), now.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("deadline %v, want %v", got, want)
+	}
+}
+
+func TestClockSimpleDelay(t *testing.T) {
+	c := NewClock(TimeControl{Base: 10 * time.Second, Delay: 2 * time.Second, DelayKind: DelaySimple})
+	c.Start(chess.White, t0)
+
+	if got, want := c.FlagDeadline(), t0.Add(12*time.Second); !got.Equal(want) {
+		t.Errorf("deadline %v, want %v", got, want)
+	}
+	if got := c.RemainingAt(chess.White, t0.Add(time.Second)); got != 10*time.Second {
+		t.Errorf("clock ran during the delay: %v left", got)
+	}
+	if got := c.RemainingAt(chess.White, t0.Add(5*time.Second)); got != 7*time.Second {
+		t.Errorf("%v left 5s into a turn with 2s delay, want 7s", got)
+	}
+
+	c.Press(t0.Add(5 * time.Second))
+	if got := c.Remaining[chess.White]; got != 7*time.Second {
+		t.Errorf("white has %v after the move, want 7s", got)
+	}
+}
+
+func TestClockFlag(t *testing.T) {
+	c := NewClock(TimeControl{Base: 10 * time.Second, Increment: 5 * time.Second})
+	c.Start(chess.White, t0)
+	deadline := c.FlagDeadline()
+
+	if c.Flagged(deadline.Add(-time.Nanosecond)) {
+		t.Error("flag fell before the deadline")
+	}
+	if !c.Flagged(deadline) {
+		t.Error("flag didn't fall exactly at the deadline")
+	}
+
+	// a move after the flag fell doesn't count and leaves the clock alone
+	if c.Press(deadline.Add(time.Second)) {
+		t.Error("Press accepted a move after the flag fell")
+	}
+	if c.Remaining[chess.White] != 10*time.Second || !c.Flagged(deadline) {
+		t.Errorf("late Press changed the clock: %v", c.Remaining)
+	}
+	if got := c.Millis(deadline.Add(time.Second)); got != [2]uint32{0, 10000} {
+		t.Errorf("Millis %v, want white clamped to 0", got)
+	}
+
+	c.Stop(deadline)
+	if c.Flagged(deadline.Add(time.Hour)) {
+		t.Error("a stopped clock flagged")
+	}
+}
@@ -0,0 +1,22 @@
+package internal
+
+import (
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+	"github.com/zefir/szaszki-go-backend/logger"
+)
+
+// EngineTracer forwards played moves to logger.Log at debug level.
+// Only installed when ENGINE_TRACE is enabled, it logs every move made in a game.
+type EngineTracer struct{}
+
+func (EngineTracer) TraceMove(e chess.MoveEvent) {
+	logger.Log.Debug().
+		Int8("from", e.Move.From).
+		Int8("to", e.Move.To).
+		Int8("promoteTo", e.Move.Promotion).
+		Int8("color", e.Color).
+		Int("piece", e.Piece).
+		Int("captured", e.Captured).
+		Uint64("hash", e.Hash).
+		Msg("Engine move")
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"sync"
+	"time"
 
 	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
 	"github.com/zefir/szaszki-go-backend/logger"
@@ -30,15 +31,51 @@ func (g *GameKeeper) CreateGame(players []*Client, mode uint16) *GameSession {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	startingBoard := chess.NewStartingPosition()
+	gamesession := g.newSession(players, mode)
+	// Start game loop in separate goroutine
+	go gamesession.Run()
+
+	return gamesession
+}
+
+// CreateBughouseGame sets up the two linked boards of a bughouse match.
+// Partners sit on different boards with opposite colors: teams[0][0] is white on the first board,
+// teams[0][1] black on the second, so captured pieces can go straight into the partner's pocket.
+func (g *GameKeeper) CreateBughouseGame(teams [2][2]*Client, mode uint16) [2]*GameSession {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	first := g.newSession([]*Client{teams[0][0], teams[1][0]}, mode)
+	second := g.newSession([]*Client{teams[1][1], teams[0][1]}, mode)
+	first.Partner, second.Partner = second, first
+
+	go first.Run()
+	go second.Run()
+
+	return [2]*GameSession{first, second}
+}
+
+// newSession registers a game, the caller holds g.mu
+func (g *GameKeeper) newSession(players []*Client, mode uint16) *GameSession {
+	variant := GameMode(mode).Variant()
+	startingBoard := variant.StartingPosition()
 	gamesession := &GameSession{
-		ID:           g.nextID,
-		Players:      players,
-		Mode:         mode,
-		Board:        startingBoard,
-		BoardHistory: []chess.Board{startingBoard},
-		SideToMove:   chess.White,
-		MoveChannel:  make(chan PlayerMove, 4),
+		ID:              g.nextID,
+		Players:         players,
+		Mode:            mode,
+		Variant:         variant,
+		Board:           startingBoard,
+		BoardHistory:    []chess.Board{startingBoard},
+		SideToMove:      chess.White,
+		MoveChannel:     make(chan PlayerMove, 4),
+		done:            make(chan struct{}),
+		ActionChannel:   make(chan PlayerAction, 4),
+		PresenceChannel: make(chan presenceEvent, 8),
+		awaySince:       make(map[*Client]time.Time),
+		PartnerChannel:  make(chan partnerEvent, 32),
+	}
+	if tc := GameMode(mode).TimeControl(); !tc.Unlimited() {
+		gamesession.Clock = NewClock(tc)
 	}
 	g.games[g.nextID] = gamesession
 	g.nextID++
@@ -48,9 +85,6 @@ func (g *GameKeeper) CreateGame(players []*Client, mode uint16) *GameSession {
 		playerIDs[i] = p.UserID
 	}
 	logger.Log.Info().Uint32("gameId", gamesession.ID).Interface("playerIDs", playerIDs).Msg("Game created")
-	// Start game loop in separate goroutine
-	go gamesession.Run()
-
 	return gamesession
 }
 
@@ -61,6 +95,29 @@ func (g *GameKeeper) GetGame(id uint32) (*GameSession, bool) {
 	return game, exists
 }
 
+func (g *GameKeeper) RemoveGame(id uint32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.games, id)
+}
+
+// GamesOf lists the running games userID plays in
+func (g *GameKeeper) GamesOf(userID uint32) []*GameSession {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var sessions []*GameSession
+	for _, game := range g.games {
+		for _, p := range game.Players {
+			if p.UserID == userID && p.Bot == nil {
+				sessions = append(sessions, game)
+				break
+			}
+		}
+	}
+	return sessions
+}
+
 func (g *GameKeeper) ListGames() []*GameSession {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -1,12 +1,25 @@
 package internal
 
+import (
+	"time"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
 type GameMode uint16
 
 const (
-	ModeClassic GameMode = 1
-	ModeRanked  GameMode = 2
-	ModeCasual  GameMode = 3
-	ModeCustom  GameMode = 4
+	ModeClassic       GameMode = 1
+	ModeRanked        GameMode = 2
+	ModeCasual        GameMode = 3
+	ModeCustom        GameMode = 4
+	ModeChess960      GameMode = 5
+	ModeKingOfTheHill GameMode = 6
+	ModeThreeCheck    GameMode = 7
+	ModeAntichess     GameMode = 8
+	ModeCrazyhouse    GameMode = 9
+	ModeBughouse      GameMode = 10
+	ModeVsComputer    GameMode = 11
 )
 
 func (m GameMode) String() string {
@@ -17,17 +30,83 @@ func (m GameMode) String() string {
 }
 
 var ModeNames = map[GameMode]string{
-	ModeClassic: "Classic",
-	ModeRanked:  "Ranked",
-	ModeCasual:  "Casual",
-	ModeCustom:  "Custom",
+	ModeClassic:       "Classic",
+	ModeRanked:        "Ranked",
+	ModeCasual:        "Casual",
+	ModeCustom:        "Custom",
+	ModeChess960:      "Chess960",
+	ModeKingOfTheHill: "King of the Hill",
+	ModeThreeCheck:    "Three-check",
+	ModeAntichess:     "Antichess",
+	ModeCrazyhouse:    "Crazyhouse",
+	ModeBughouse:      "Bughouse",
+	ModeVsComputer:    "vs Computer",
 }
 
+// AvailableModes get a matchmaker queue each, computer games start right away and don't need one
 var AvailableModes = []GameMode{
 	ModeClassic,
 	ModeRanked,
 	ModeCasual,
 	ModeCustom,
+	ModeChess960,
+	ModeKingOfTheHill,
+	ModeThreeCheck,
+	ModeAntichess,
+	ModeCrazyhouse,
+	ModeBughouse,
+}
+
+// ModeVariants maps modes to the rules they are played by, modes missing here play standard chess
+var ModeVariants = map[GameMode]chess.Variant{
+	ModeChess960:      chess.FischerRandom,
+	ModeKingOfTheHill: chess.KingOfTheHill,
+	ModeThreeCheck:    chess.ThreeCheck,
+	ModeAntichess:     chess.Antichess,
+	ModeCrazyhouse:    chess.Crazyhouse,
+	ModeBughouse:      chess.Bughouse,
+}
+
+// PlayersNeeded is how many players the matchmaker groups into one game
+func (m GameMode) PlayersNeeded() int {
+	if m == ModeBughouse {
+		return 4
+	}
+	return 2
+}
+
+func (m GameMode) Variant() chess.Variant {
+	if variant, ok := ModeVariants[m]; ok {
+		return variant
+	}
+	return chess.StandardChess
+}
+
+// ModeTimeControls are the default clocks, modes missing here are played without one
+var ModeTimeControls = map[GameMode]TimeControl{
+	ModeClassic:       {Base: 10 * time.Minute},
+	ModeRanked:        {Base: 10 * time.Minute, Increment: 5 * time.Second},
+	ModeCasual:        {Base: 15 * time.Minute, Increment: 10 * time.Second},
+	ModeChess960:      {Base: 10 * time.Minute, Increment: 5 * time.Second},
+	ModeKingOfTheHill: {Base: 5 * time.Minute, Increment: 3 * time.Second},
+	ModeThreeCheck:    {Base: 5 * time.Minute, Increment: 3 * time.Second},
+	ModeAntichess:     {Base: 3 * time.Minute, Increment: 2 * time.Second},
+	ModeCrazyhouse:    {Base: 3 * time.Minute, Increment: 2 * time.Second},
+	ModeBughouse:      {Base: 3 * time.Minute, Delay: 2 * time.Second, DelayKind: DelaySimple},
+}
+
+func (m GameMode) TimeControl() TimeControl {
+	return ModeTimeControls[m]
+}
+
+// ModesWithoutTakebacks can't undo moves: ranked games count, bughouse captures already went to the partner board
+var ModesWithoutTakebacks = map[GameMode]bool{
+	ModeRanked:   true,
+	ModeBughouse: true,
+}
+
+func (m GameMode) TakebacksAllowed() bool {
+	return !ModesWithoutTakebacks[m]
 }
 
 func GetAllModes() []uint16 {
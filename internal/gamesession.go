@@ -2,9 +2,11 @@ package internal
 
 import (
 	"encoding/json"
-	"log"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/zefir/szaszki-go-backend/config"
 	"github.com/zefir/szaszki-go-backend/grpc"
 	bh "github.com/zefir/szaszki-go-backend/internal/binaryHelpers"
 	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
@@ -14,36 +16,86 @@ import (
 )
 
 type GameSession struct {
-	ID           uint32
-	Players      []*Client
-	Mode         uint16
-	Board        chess.Board
-	BoardHistory []chess.Board
-	MoveHistory  []chess.Move
-	SideToMove   int // 0 = White, 1 = Black
-	MoveChannel  chan PlayerMove
-	GameActive   bool
-	Mu           sync.RWMutex
+	ID            uint32
+	Players       []*Client
+	Mode          uint16
+	Variant       chess.Variant
+	Board         chess.Board
+	BoardHistory  []chess.Board
+	MoveHistory   []chess.Move
+	SideToMove    int // chess.White or chess.Black, Players[0] plays white
+	MoveChannel   chan PlayerMove
+	ActionChannel chan PlayerAction
+	GameActive    bool
+	Result        chess.Result
+	Termination   chess.Termination
+	Clock         *Clock        // nil when the mode is played without one
+	drawOffer     *Client       // player whose draw offer is open, nil if none
+	takeback      *Client       // player asking to undo their last move, nil if none
+	botRequest    uint32        // latest bot search asked for, answers to older ones are stale
+	startMsg      []byte        // GameStarted payload, sent again to players who reconnect
+	done          chan struct{} // closed by endGame, Run no longer reads the channels after that
+	doneOnce      sync.Once
+	Mu            sync.RWMutex
+
+	// players whose connections all dropped, the game waits config.AppConfig.RECONNECT_GRACE for them
+	PresenceChannel chan presenceEvent
+	awaySince       map[*Client]time.Time
+
+	// bughouse: the other board of the match and what it tells us about captures and its result
+	Partner        *GameSession
+	PartnerChannel chan partnerEvent
+	partnerBacklog []partnerEvent // taken off PartnerChannel while notifyPartner waited, Run handles them first
+}
+
+type partnerEvent struct {
+	Color       int8 // pocket that receives Piece
+	Piece       int
+	GameOver    bool
+	Result      chess.Result // from this board's point of view
+	Termination chess.Termination
+}
+
+// presenceEvent tells the game that Player's connections changed, it checks IsConnected itself
+type presenceEvent struct {
+	Player *Client
 }
 
 type PlayerMove struct {
-	From      int8
-	To        int8
-	PromoteTo int8
-	Player    *Client
+	From       int8
+	To         int8
+	PromoteTo  int8
+	Player     *Client
+	BotRequest uint32 // set by the bot to the request it answers
+}
+
+// PlayerAction is any of the non-move game commands (resign, draw offers and claims, abort, takebacks)
+type PlayerAction struct {
+	Cmd    MsgType // one of ClientCmds
+	Player *Client
 }
 
 type GameStartMsg struct {
-	GameMode  uint16 `json:"game_mode"`
-	PlayerIDs []int  `json:"player_ids"`
-	GameID    uint32 `json:"game_id"`
+	GameMode  uint16    `json:"game_mode"`
+	PlayerIDs []int     `json:"player_ids"`
+	GameID    uint32    `json:"game_id"`
+	FEN       string    `json:"fen"`
+	PartnerID uint32    `json:"partner_game_id,omitempty"` // other board of a bughouse match
+	Clock     *ClockMsg `json:"clock,omitempty"`
+}
+
+type ClockMsg struct {
+	BaseMs      int64     `json:"base_ms"`
+	IncrementMs int64     `json:"increment_ms"`
+	DelayMs     int64     `json:"delay_ms"`
+	DelayKind   DelayKind `json:"delay_kind"` // 0 none, 1 Bronstein, 2 simple
 }
 
 func (g *GameSession) Run() {
 	logger.Log.Info().Uint32("gameId", g.ID).Msg("Game started!")
 
-	g.Board = chess.NewStartingPosition()
 	g.SideToMove = chess.White
+	g.GameActive = true
 
 	var playerIDs []int
 	for _, p := range g.Players {
@@ -55,6 +107,18 @@ func (g *GameSession) Run() {
 		GameMode:  g.Mode,
 		PlayerIDs: playerIDs,
 		GameID:    g.ID,
+		FEN:       g.Board.ShredderFEN(),
+	}
+	if g.Partner != nil {
+		msg.PartnerID = g.Partner.ID
+	}
+	if g.Clock != nil {
+		msg.Clock = &ClockMsg{
+			BaseMs:      g.Clock.Base.Milliseconds(),
+			IncrementMs: g.Clock.Increment.Milliseconds(),
+			DelayMs:     g.Clock.Delay.Milliseconds(),
+			DelayKind:   g.Clock.DelayKind,
+		}
 	}
 
 	data, err := json.Marshal(msg)
@@ -62,6 +126,7 @@ func (g *GameSession) Run() {
 		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("error marshaling game start message")
 		return
 	}
+	g.startMsg = data
 
 	for _, player := range g.Players {
 		err := player.WriteMsg(ServerCmds.GameStarted, data)
@@ -70,49 +135,548 @@ func (g *GameSession) Run() {
 		}
 	}
 
+	// the timer fires when the side to move's flag would fall
+	var flag <-chan time.Time
+	var flagTimer *time.Timer
+	if g.Clock != nil {
+		g.Clock.Start(g.Board.ActiveColor(), time.Now())
+		flagTimer = time.NewTimer(time.Until(g.Clock.FlagDeadline()))
+		defer flagTimer.Stop()
+		flag = flagTimer.C
+	}
+
+	// fires when a missing player's grace period is over, players may have dropped before the game started
+	g.Mu.Lock()
+	g.updateAway(time.Now())
+	g.Mu.Unlock()
+	graceTimer := time.NewTimer(0)
+	graceTimer.Stop()
+	defer graceTimer.Stop()
+
+	g.requestBotMove()
+
 	// Game loop
 	for {
-		// wait for move from current player
-		move := <-g.MoveChannel
+		var grace <-chan time.Time
+		if deadline, ok := g.graceDeadline(); ok {
+			graceTimer.Reset(time.Until(deadline))
+			grace = graceTimer.C
+		} else {
+			graceTimer.Stop()
+		}
+
+		if len(g.partnerBacklog) > 0 {
+			event := g.partnerBacklog[0]
+			g.partnerBacklog = g.partnerBacklog[1:]
+			if g.handlePartnerEvent(event) {
+				return
+			}
+			continue
+		}
+
+		// wait for move from current player, news from the partner board, the flag or the grace period
+		var move PlayerMove
+		select {
+		case move = <-g.MoveChannel:
+		case action := <-g.ActionChannel:
+			if g.handleAction(action) {
+				return
+			}
+			if g.Clock != nil {
+				// a takeback may have handed the move back
+				flagTimer.Reset(time.Until(g.Clock.FlagDeadline()))
+			}
+			continue
+		case event := <-g.PresenceChannel:
+			g.handlePresence(event)
+			continue
+		case <-grace:
+			if g.shouldEndGame() {
+				g.endGame(g.abandonmentResult(), chess.TerminationAbandoned)
+				return
+			}
+			continue
+		case event := <-g.PartnerChannel:
+			if g.handlePartnerEvent(event) {
+				return
+			}
+			continue
+		case <-flag:
+			if g.Clock.Flagged(time.Now()) {
+				g.flagFell()
+				return
+			}
+			flagTimer.Reset(time.Until(g.Clock.FlagDeadline()))
+			continue
+		}
 		logger.Log.Info().Uint32("gameId", g.ID).Int("from", int(move.From)).Int("to", int(move.To)).Int("promoteTo", int(move.PromoteTo)).Uint32("playerId", move.Player.UserID).Msg("Received move")
 
-		// Confirm move came from the correct player
-		// if g.Players[g.SideToMove] != move.Player {
-		// 	logger.Log.Warn().Uint32("playerId", move.Player.UserID).Uint32("gameId", g.ID).Msg("ignoring move from wrong player")
-		// 	continue
-		// }
+		if move.Player.Bot != nil && move.BotRequest != g.botRequest {
+			// searched in a position that was taken back since
+			logger.Log.Debug().Uint32("gameId", g.ID).Uint32("request", move.BotRequest).Msg("dropping stale bot move")
+			continue
+		}
 
-		//is move by correct palyer
+		// Confirm move came from the player whose turn it is
+		if color, ok := g.PlayerColor(move.Player); !ok || color != g.Board.ActiveColor() {
+			logger.Log.Warn().Uint32("playerId", move.Player.UserID).Uint32("gameId", g.ID).Msg("ignoring move from wrong player")
+			sendInvalidMove(move.Player, g.ID, InvalidMoveNotYourTurn)
+			continue
+		}
 
 		// check legality
-		if !chess.IsMoveLegal(&g.Board, move.From, move.To, move.PromoteTo) {
+		candidate := chess.Move{From: move.From, To: move.To, Promotion: move.PromoteTo}
+		if !chess.IsVariantMoveLegal(g.Variant, &g.Board, g.BoardHistory, candidate) {
+			if bot := move.Player.Bot; bot != nil {
+				// the engine disagrees with our rules, the built-in search moves instead
+				logger.Log.Warn().Uint32("gameId", g.ID).Int("from", int(move.From)).Int("to", int(move.To)).Msg("bot played an illegal move")
+				bot.Reject()
+				g.requestBotMove()
+				continue
+			}
 			// reject move, ask player again
+			sendInvalidMove(move.Player, g.ID, InvalidMoveIllegal)
 			continue
 		}
 
-		madeMove := chess.MakeMove(&g.Board, move.From, move.To, move.PromoteTo)
-		g.MoveHistory = append(g.MoveHistory, madeMove)
+		if g.Clock != nil {
+			if !g.Clock.Press(time.Now()) {
+				g.flagFell()
+				return
+			}
+			flagTimer.Reset(time.Until(g.Clock.FlagDeadline()))
+		}
+
+		undo := g.Variant.MakeMove(&g.Board, candidate)
+		chess.TraceMove(&g.Board, undo)
+		g.MoveHistory = append(g.MoveHistory, undo.Move)
 		g.BoardHistory = append(g.BoardHistory, g.Board)
 
+		if piece := undo.PocketPiece(); piece >= 0 && g.Partner != nil {
+			// the captured piece joins the partner, who plays the captured side's color
+			g.notifyPartner(partnerEvent{Color: g.Board.ActiveColor(), Piece: piece})
+		}
+
+		// moving instead of answering lets the opponent's draw offer lapse
+		if g.drawOffer != nil && g.drawOffer != move.Player {
+			g.drawOffer = nil
+		}
+		// a pending takeback request is about a position that's gone now
+		g.takeback = nil
+
 		// update side to move
 		g.SideToMove = 1 - g.SideToMove
 
 		g.BroadcastMove(move.From, move.To, move.PromoteTo)
 
-		// TODO: check for game end (checkmate, stalemate, etc)
+		if result, reason := g.outcome(); result != chess.ResultNone {
+			g.endGame(result, reason)
+			break
+		}
+
 		if g.shouldEndGame() {
-			g.saveGame()
+			g.endGame(g.abandonmentResult(), chess.TerminationAbandoned)
 			break
 		}
+
+		g.requestBotMove()
 	}
 }
 
-func (g *GameSession) BroadcastMove(from, to, promote int8) {
+func (g *GameSession) endGame(result chess.Result, reason chess.Termination) {
+	g.doneOnce.Do(func() { close(g.done) })
 
-	log.Printf("Broadcasting move: from=%d (%T), to=%d (%T), promote=%d (%T), g.ID=%d",
-		from, from, to, to, promote, promote, g.ID,
-	)
-	payload, err := bh.Pack([]bh.FieldType{bh.Int8, bh.Int8, bh.Int8, bh.Uint32}, []any{from, to, promote, g.ID})
+	g.Mu.Lock()
+	g.GameActive = false
+	g.Result = result
+	g.Termination = reason
+	if g.Clock != nil {
+		g.Clock.Stop(time.Now())
+	}
+	g.Mu.Unlock()
+
+	logger.Log.Info().Uint32("gameId", g.ID).Str("result", result.String()).Str("reason", reason.String()).Msg("Game over")
+
+	g.BroadcastGameOver(result, reason)
+	for _, p := range g.Players {
+		p.CurrentlyPlaying = false
+		if p.Bot != nil {
+			p.Bot.Close()
+		}
+	}
+
+	// saving is a round trip, the game mustn't be found in the meantime
+	GetGameKeeper().RemoveGame(g.ID)
+	if reason != chess.TerminationAborted {
+		g.saveGame(result)
+	}
+
+	if g.Partner != nil {
+		// partners play opposite colors, so a white win here is a black win there
+		g.notifyPartner(partnerEvent{GameOver: true, Result: oppositeResult(result), Termination: reason})
+	}
+}
+
+// Done is closed once the game is over
+func (g *GameSession) Done() <-chan struct{} {
+	return g.done
+}
+
+// PlayerColor is the color client plays in this game, Players[0] is white
+func (g *GameSession) PlayerColor(client *Client) (int8, bool) {
+	for i, p := range g.Players {
+		if p == client {
+			if i == 0 {
+				return chess.White, true
+			}
+			return chess.Black, true
+		}
+	}
+	return 0, false
+}
+
+// PlayerOf is the client playing color
+func (g *GameSession) PlayerOf(color int8) *Client {
+	if color == chess.White {
+		return g.Players[0]
+	}
+	return g.Players[1]
+}
+
+// handleAction applies a resign, draw or abort command, reports whether the game ended
+func (g *GameSession) handleAction(action PlayerAction) bool {
+	color, ok := g.PlayerColor(action.Player)
+	if !ok {
+		sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNotInGame)
+		return false
+	}
+	opponent := g.PlayerOf(1 - color)
+
+	switch action.Cmd {
+	case ClientCmds.Resign:
+		g.endGame(chess.WinFor(1-color), chess.TerminationResignation)
+		return true
+	case ClientCmds.OfferDraw:
+		switch g.drawOffer {
+		case opponent:
+			// both want a draw
+			g.endGame(chess.ResultDraw, chess.TerminationAgreement)
+			return true
+		case action.Player:
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectOfferPending)
+			return false
+		}
+		if opponent.Bot != nil {
+			// the computer plays on
+			g.sendToPlayer(action.Player, ServerCmds.DrawDeclined)
+			return false
+		}
+		g.drawOffer = action.Player
+		g.sendToPlayer(opponent, ServerCmds.DrawOffered)
+	case ClientCmds.AcceptDraw:
+		if g.drawOffer != opponent {
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNoDrawOffer)
+			return false
+		}
+		g.endGame(chess.ResultDraw, chess.TerminationAgreement)
+		return true
+	case ClientCmds.DeclineDraw:
+		if g.drawOffer != opponent {
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNoDrawOffer)
+			return false
+		}
+		g.drawOffer = nil
+		g.sendToPlayer(opponent, ServerCmds.DrawDeclined)
+	case ClientCmds.ClaimDraw:
+		reason := chess.ClaimableDraw(&g.Board, g.BoardHistory)
+		if reason == chess.TerminationNone {
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNoClaim)
+			return false
+		}
+		g.endGame(chess.ResultDraw, reason)
+		return true
+	case ClientCmds.Abort:
+		if g.hasMoved(color) {
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectTooLate)
+			return false
+		}
+		g.endGame(chess.ResultNone, chess.TerminationAborted)
+		return true
+	case ClientCmds.RequestTakeback:
+		switch {
+		case !GameMode(g.Mode).TakebacksAllowed():
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNoTakebacks)
+		case !g.hasMoved(color):
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNothingToUndo)
+		case g.takeback == action.Player:
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectOfferPending)
+		case opponent.Bot != nil:
+			// the computer always agrees
+			g.takeBack(color)
+		default:
+			g.takeback = action.Player
+			g.sendToPlayer(opponent, ServerCmds.TakebackRequested)
+		}
+	case ClientCmds.AcceptTakeback:
+		if g.takeback != opponent {
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNoTakeback)
+			return false
+		}
+		g.takeBack(1 - color)
+	case ClientCmds.DeclineTakeback:
+		if g.takeback != opponent {
+			sendActionRejected(action.Player, g.ID, action.Cmd, ActionRejectNoTakeback)
+			return false
+		}
+		g.takeback = nil
+		g.sendToPlayer(opponent, ServerCmds.TakebackDeclined)
+	}
+	return false
+}
+
+// takeBack undoes color's last move, along with the opponent's reply if there was one,
+// so it's color's turn again
+func (g *GameSession) takeBack(color int8) {
+	plies := 1
+	if g.Board.ActiveColor() == color {
+		plies = 2
+	}
+	g.Mu.Lock()
+	g.MoveHistory = g.MoveHistory[:len(g.MoveHistory)-plies]
+	g.BoardHistory = g.BoardHistory[:len(g.BoardHistory)-plies]
+	g.Board = g.BoardHistory[len(g.BoardHistory)-1]
+	g.SideToMove = int(g.Board.ActiveColor())
+	g.Mu.Unlock()
+	g.takeback = nil
+	g.drawOffer = nil
+	g.botRequest++ // a search still running is for the old position
+
+	if g.Clock != nil {
+		// times stay as they were, only the running side changes
+		now := time.Now()
+		g.Clock.Stop(now)
+		g.Clock.Start(g.Board.ActiveColor(), now)
+	}
+	logger.Log.Info().Uint32("gameId", g.ID).Int("plies", plies).Msg("Move taken back")
+
+	format := []bh.FieldType{bh.Uint32, bh.Uint8}
+	values := []any{g.ID, uint8(plies)}
+	if g.Clock != nil {
+		ms := g.Clock.Millis(time.Now())
+		format = append(format, bh.Uint32, bh.Uint32)
+		values = append(values, ms[0], ms[1])
+	}
+	payload, err := bh.Pack(format, values)
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("couldnt pack takeback")
+		return
+	}
+	for _, p := range g.Players {
+		_ = p.WriteMsg(ServerCmds.TakebackDone, payload)
+	}
+	g.broadcastGameState()
+}
+
+// outcome is the variant's verdict on the position. The computer never claims a draw,
+// so its games end on threefold repetition and the fifty-move rule right away.
+func (g *GameSession) outcome() (chess.Result, chess.Termination) {
+	result, reason := g.Variant.Outcome(&g.Board, g.BoardHistory)
+	if result != chess.ResultNone || !g.hasBot() {
+		return result, reason
+	}
+	if reason = chess.ClaimableDraw(&g.Board, g.BoardHistory); reason != chess.TerminationNone {
+		return chess.ResultDraw, reason
+	}
+	return chess.ResultNone, chess.TerminationNone
+}
+
+func (g *GameSession) hasBot() bool {
+	for _, p := range g.Players {
+		if p.Bot != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMoved reports whether color made a move in this game yet
+func (g *GameSession) hasMoved(color int8) bool {
+	for _, board := range g.BoardHistory[:len(g.BoardHistory)-1] {
+		if board.ActiveColor() == color {
+			return true
+		}
+	}
+	return false
+}
+
+// sendToPlayer sends a message whose payload is just the game id
+func (g *GameSession) sendToPlayer(player *Client, msgType MsgType) {
+	payload, err := bh.Pack([]bh.FieldType{bh.Uint32}, []any{g.ID})
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("couldnt pack game id")
+		return
+	}
+	_ = player.WriteMsg(msgType, payload)
+}
+
+// flagFell ends the game for the side to move, whose time ran out.
+// It's a draw if the opponent couldn't mate anyway.
+func (g *GameSession) flagFell() {
+	loser := g.Board.ActiveColor()
+	if !chess.HasMatingMaterial(&g.Board, 1-loser) {
+		g.endGame(chess.ResultDraw, chess.TerminationTimeoutVsInsufficientMaterial)
+		return
+	}
+	g.endGame(chess.WinFor(1-loser), chess.TerminationTimeout)
+}
+
+// handlePartnerEvent applies what happened on the linked bughouse board, reports whether this game ended
+func (g *GameSession) handlePartnerEvent(event partnerEvent) bool {
+	if event.GameOver {
+		g.Partner = nil // it's over there already, nothing to report back
+		g.endGame(event.Result, event.Termination)
+		return true
+	}
+	g.Board.AddToPocket(event.Color, event.Piece)
+	g.BroadcastPockets()
+	return false
+}
+
+// handlePresence starts or ends a player's grace period, a player who's connected gets a full snapshot
+func (g *GameSession) handlePresence(event presenceEvent) {
+	g.Mu.Lock()
+	g.updateAway(time.Now())
+	g.Mu.Unlock()
+
+	if !event.Player.IsConnected() {
+		return
+	}
+	_ = event.Player.WriteMsg(ServerCmds.GameStarted, g.startMsg)
+	for i, p := range g.Players {
+		if p == event.Player {
+			g.sendGameState(i, p)
+		}
+	}
+}
+
+// updateAway records when players went missing and forgets the ones who came back, the caller holds g.Mu
+func (g *GameSession) updateAway(now time.Time) {
+	for _, p := range g.Players {
+		if p.IsConnected() {
+			if _, ok := g.awaySince[p]; ok {
+				logger.Log.Info().Uint32("gameId", g.ID).Uint32("playerId", p.UserID).Msg("Player reconnected")
+			}
+			delete(g.awaySince, p)
+		} else if _, ok := g.awaySince[p]; !ok {
+			logger.Log.Info().Uint32("gameId", g.ID).Uint32("playerId", p.UserID).Dur("grace", config.AppConfig.RECONNECT_GRACE).Msg("Player disconnected, waiting for reconnect")
+			g.awaySince[p] = now
+		}
+	}
+}
+
+// graceDeadline is when the first missing player runs out of grace, ok is false if everyone is here
+func (g *GameSession) graceDeadline() (deadline time.Time, ok bool) {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	for _, since := range g.awaySince {
+		if d := since.Add(config.AppConfig.RECONNECT_GRACE); !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
+	}
+	return deadline, ok
+}
+
+// notifyPresence waits for Run to take the event, a lost disconnect would leave the game waiting forever
+func (g *GameSession) notifyPresence(client *Client) {
+	select {
+	case g.PresenceChannel <- presenceEvent{Player: client}:
+	case <-g.done:
+	}
+}
+
+// notifyPartner waits for the partner board to take the event or finish. Both boards
+// may be waiting on each other, so what the partner sends meanwhile is kept for Run
+func (g *GameSession) notifyPartner(event partnerEvent) {
+	for {
+		select {
+		case g.Partner.PartnerChannel <- event:
+			return
+		case <-g.Partner.Done():
+			return
+		case incoming := <-g.PartnerChannel:
+			g.partnerBacklog = append(g.partnerBacklog, incoming)
+		}
+	}
+}
+
+func oppositeResult(result chess.Result) chess.Result {
+	switch result {
+	case chess.ResultWhiteWins:
+		return chess.ResultBlackWins
+	case chess.ResultBlackWins:
+		return chess.ResultWhiteWins
+	}
+	return result
+}
+
+// BroadcastPockets sends both pockets, white pawn..queen then black pawn..queen
+func (g *GameSession) BroadcastPockets() {
+	payload, err := bh.Pack([]bh.FieldType{bh.Uint32}, []any{g.ID})
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("couldnt pack pockets")
+		return
+	}
+	payload = append(payload, pocketBytes(&g.Board)...)
+
+	for _, p := range g.Players {
+		_ = p.WriteMsg(ServerCmds.PocketsChanged, payload)
+	}
+}
+
+func pocketBytes(board *chess.Board) []byte {
+	pockets := make([]byte, 0, 10)
+	for _, color := range []int8{chess.White, chess.Black} {
+		pockets = append(pockets, board.Pockets[color][:]...)
+	}
+	return pockets
+}
+
+func (g *GameSession) BroadcastGameOver(result chess.Result, reason chess.Termination) {
+	payload, err := bh.Pack([]bh.FieldType{bh.Uint32, bh.Uint8, bh.Uint8}, []any{g.ID, uint8(result), uint8(reason)})
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("couldnt pack game over")
+		return
+	}
+
+	for _, p := range g.Players {
+		_ = p.WriteMsg(ServerCmds.GameOver, payload)
+	}
+}
+
+// abandonmentResult awards the game to whoever is still connected (Players[0] is white)
+func (g *GameSession) abandonmentResult() chess.Result {
+	whiteConnected := g.Players[0].IsConnected()
+	blackConnected := g.Players[1].IsConnected()
+
+	switch {
+	case whiteConnected && !blackConnected:
+		return chess.ResultWhiteWins
+	case blackConnected && !whiteConnected:
+		return chess.ResultBlackWins
+	}
+	return chess.ResultDraw
+}
+
+// BroadcastMove sends the move, followed by white's and black's remaining milliseconds when the game has a clock
+func (g *GameSession) BroadcastMove(from, to, promote int8) {
+	format := []bh.FieldType{bh.Int8, bh.Int8, bh.Int8, bh.Uint32}
+	values := []any{from, to, promote, g.ID}
+	if g.Clock != nil {
+		ms := g.Clock.Millis(time.Now())
+		format = append(format, bh.Uint32, bh.Uint32)
+		values = append(values, ms[0], ms[1])
+	}
+	payload, err := bh.Pack(format, values)
 	if err != nil {
 		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("couldnt pack move")
 		return
@@ -123,28 +687,28 @@ func (g *GameSession) BroadcastMove(from, to, promote int8) {
 	}
 }
 
+// shouldEndGame reports whether a player has been gone for longer than the reconnect grace period
 func (g *GameSession) shouldEndGame() bool {
-	g.Mu.RLock()
-	defer g.Mu.RUnlock()
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
 
 	// Check if game is too old
 	// if time.Since(g.LastActivity) > 10*time.Minute {
 	// 	return true
 	// }
 
-	// Check if players are still connected
-	connectedPlayers := 0
-	for _, player := range g.Players {
-		if player.ConnCount() > 0 && !player.IsDisconnected() {
-			connectedPlayers++
+	// Check if a missing player ran out of time to reconnect
+	now := time.Now()
+	g.updateAway(now)
+	for _, since := range g.awaySince {
+		if now.Sub(since) >= config.AppConfig.RECONNECT_GRACE {
+			return true
 		}
 	}
-
-	// End game if less than 2 players connected
-	return connectedPlayers < 2
+	return false
 }
 
-func (g *GameSession) saveGame() {
+func (g *GameSession) saveGame(result chess.Result) {
 	// Convert board history to byte slices
 	var boardHistoryBytes [][]byte
 	for _, board := range g.BoardHistory {
@@ -162,7 +726,21 @@ func (g *GameSession) saveGame() {
 		MoveHistory:  moveHistoryProto,
 	}
 
-	pgn := g.Board.ToPGN(g.MoveHistory)
+	tags := chess.PGNTags{
+		Event:       "Szaszki " + GameMode(g.Mode).String() + " game",
+		Site:        "Szaszki",
+		Date:        time.Now().Format("2006.01.02"),
+		Round:       "-",
+		White:       playerName(g.Players[0]),
+		Black:       playerName(g.Players[1]),
+		Result:      result,
+		Mode:        GameMode(g.Mode).String(),
+		TimeControl: GameMode(g.Mode).TimeControl().String(),
+	}
+	if g.Variant != chess.StandardChess {
+		tags.Variant = g.Variant.Name()
+	}
+	pgn := chess.WritePGN(tags, g.BoardHistory[0], g.MoveHistory)
 
 	_, err := grpc.SaveGame(g.ID, g.Players[0].UserID, g.Players[1].UserID, gameState, pgn)
 	if err != nil {
@@ -170,10 +748,27 @@ func (g *GameSession) saveGame() {
 	}
 }
 
+func playerName(p *Client) string {
+	if p.Bot != nil {
+		return "Computer (level " + strconv.Itoa(p.Bot.Level) + ")"
+	}
+	return strconv.FormatUint(uint64(p.UserID), 10)
+}
+
 func (g *GameSession) broadcastGameState() {
 	if !g.GameActive {
 		return
 	}
+	for i, player := range g.Players {
+		g.sendGameState(i, player)
+	}
+}
+
+// sendGameState sends the full position to Players[i], with both clocks (white first, ms) after the pockets when the game has one
+func (g *GameSession) sendGameState(i int, player *Client) {
+	if player.ConnCount() == 0 {
+		return
+	}
 
 	// Convert bitboard representation to square array
 	squareArray := g.Board.ToSquareArray()
@@ -184,37 +779,43 @@ func (g *GameSession) broadcastGameState() {
 		boardBytes[i] = byte(squareArray[i])
 	}
 
-	for i, player := range g.Players {
-		if player.ConnCount() == 0 {
-			continue
-		}
+	sideToMove := g.Board.SideToMove()
+
+	// Pack complete game state
+	payload, err := bh.Pack(
+		[]bh.FieldType{bh.Uint32, bh.Uint8, bh.Uint8, bh.Uint8, bh.Int8, bh.Uint8, bh.Uint16},
+		[]any{
+			g.ID,
+			uint8(i),
+			sideToMove,
+			g.Board.Flags & 15, // Only castling bits (mask out WhiteToMove bit)
+			g.Board.EnPassantSquare,
+			g.Board.HalfmoveClock,
+			g.Board.FullmoveNumber,
+		},
+	)
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("error packing game state header")
+		return
+	}
 
-		sideToMove := g.Board.SideToMove()
-
-		// Pack complete game state
-		payload, err := bh.Pack(
-			[]bh.FieldType{bh.Uint32, bh.Uint8, bh.Uint8, bh.Uint8, bh.Int8, bh.Uint8, bh.Uint16},
-			[]any{
-				g.ID,
-				uint8(i),
-				sideToMove,
-				g.Board.Flags & 15, // Only castling bits (mask out WhiteToMove bit)
-				g.Board.EnPassantSquare,
-				g.Board.HalfmoveClock,
-				g.Board.FullmoveNumber,
-			},
-		)
+	// Append board data
+	payload = append(payload, boardBytes...)
+	if g.Board.Flags&chess.DropsAllowed != 0 {
+		payload = append(payload, pocketBytes(&g.Board)...)
+	}
+	if g.Clock != nil {
+		ms := g.Clock.Millis(time.Now())
+		clocks, err := bh.Pack([]bh.FieldType{bh.Uint32, bh.Uint32}, []any{ms[0], ms[1]})
 		if err != nil {
-			logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("error packing game state header")
-			continue
+			logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Msg("error packing clocks")
+			return
 		}
+		payload = append(payload, clocks...)
+	}
 
-		// Append board data
-		payload = append(payload, boardBytes...)
-
-		err = player.WriteMsg(ServerCmds.GameState, payload)
-		if err != nil {
-			logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Uint32("playerId", player.UserID).Msg("error sending game state to player")
-		}
+	err = player.WriteMsg(ServerCmds.GameState, payload)
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", g.ID).Uint32("playerId", player.UserID).Msg("error sending game state to player")
 	}
 }
@@ -69,45 +69,51 @@ ProcessMatches:
 	}
 
 	filtered := waitingList[:0] // reuse underlying array to reduce allocations
+	needed := GameMode(m.mode).PlayersNeeded()
+	group := make([]*Client, 0, needed)
 
-	for i := 0; i < len(waitingList); {
-		p1 := waitingList[i]
-		i++
-
+	for _, p := range waitingList {
 		// Drop disconnected player
-		if m.isClientDisconnected(p1) {
-			logger.Log.Info().Uint32("clientId", p1.UserID).Uint16("mode", m.mode).Msg("Dropping disconnected client from mode")
+		if m.isClientDisconnected(p) {
+			logger.Log.Info().Uint32("clientId", p.UserID).Uint16("mode", m.mode).Msg("Dropping disconnected client from mode")
 			continue
 		}
 
-		// Not enough players left for a match
-		if i >= len(waitingList) {
-			// Keep the leftover connected player
-			filtered = append(filtered, p1)
-			break
-		}
-
-		p2 := waitingList[i]
-		i++
-
-		// Drop disconnected second player and push first back into filtered list
-		if m.isClientDisconnected(p2) {
-			logger.Log.Info().Uint32("clientId", p2.UserID).Uint16("mode", m.mode).Msg("Dropping disconnected client from mode")
-			filtered = append(filtered, p1)
+		if containsClient(group, p) {
+			logger.Log.Warn().Uint32("clientId", p.UserID).Uint16("mode", m.mode).Msg("Skipping: same player queued twice")
 			continue
 		}
 
-		if p1.UserID == p2.UserID {
-			logger.Log.Warn().Uint32("clientId", p1.UserID).Uint16("mode", m.mode).Msg("Skipping match: same player queued twice")
+		group = append(group, p)
+		if len(group) < needed {
 			continue
 		}
 
-		// ✅ Both connected: create match
-		logger.Log.Info().Uint32("p1_clientId", p1.UserID).Uint32("p2_clientId", p2.UserID).Uint16("mode", m.mode).Msg("Matched clients in mode")
-		m.startGame([]*Client{p1, p2})
+		// ✅ Everyone connected: create match
+		logger.Log.Info().Interface("clientIds", clientIDs(group)).Uint16("mode", m.mode).Msg("Matched clients in mode")
+		m.startGame(group)
+		group = make([]*Client, 0, needed)
 	}
 
-	return filtered
+	// Keep the leftover connected players for the next round
+	return append(filtered, group...)
+}
+
+func containsClient(list []*Client, target *Client) bool {
+	for _, p := range list {
+		if p.UserID == target.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+func clientIDs(clients []*Client) []uint32 {
+	ids := make([]uint32, len(clients))
+	for i, c := range clients {
+		ids[i] = c.UserID
+	}
+	return ids
 }
 
 // Helper method to check if client is disconnected
@@ -191,6 +197,17 @@ func (m *Matchmaker) ForceProcess() {
 }
 
 func (m *Matchmaker) startGame(players []*Client) {
+	if GameMode(m.mode) == ModeBughouse {
+		// queue order decides the teams: the first two against the last two
+		teams := [2][2]*Client{{players[0], players[1]}, {players[2], players[3]}}
+		logger.Log.Info().
+			Interface("teamA", clientIDs(teams[0][:])).
+			Interface("teamB", clientIDs(teams[1][:])).
+			Uint16("mode", m.mode).
+			Msg("Starting bughouse match")
+		GetGameKeeper().CreateBughouseGame(teams, m.mode)
+		return
+	}
 
 	logger.Log.Info().
 		Uint32("whitePlayerId", players[0].UserID).
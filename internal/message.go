@@ -22,7 +22,15 @@ var ServerCmds = struct {
 	GameSearchTimeout    MsgType
 	MoveHappend          MsgType
 	InvalidMove          MsgType
+	GameOver             MsgType
+	PocketsChanged       MsgType
 	GameState            MsgType
+	DrawOffered          MsgType
+	DrawDeclined         MsgType
+	ActionRejected       MsgType
+	TakebackRequested    MsgType
+	TakebackDeclined     MsgType
+	TakebackDone         MsgType
 }{
 	Ping:                 1,
 	OutMsgUpdateVariable: 2,
@@ -33,9 +41,45 @@ var ServerCmds = struct {
 	GameSearchTimeout:    7,
 	MoveHappend:          15,
 	InvalidMove:          16,
+	GameOver:             17,
+	PocketsChanged:       18,
 	GameState:            20,
+	DrawOffered:          21,
+	DrawDeclined:         22,
+	ActionRejected:       23,
+	TakebackRequested:    24,
+	TakebackDeclined:     25,
+	TakebackDone:         26,
 }
 
+// InvalidMoveReason is sent with InvalidMove after the game id
+type InvalidMoveReason uint8
+
+const (
+	InvalidMoveMalformed   InvalidMoveReason = 1 // payload couldn't be read
+	InvalidMoveNoGame      InvalidMoveReason = 2 // no active game with that id
+	InvalidMoveNotInGame   InvalidMoveReason = 3 // sender doesn't play in that game
+	InvalidMoveNotYourTurn InvalidMoveReason = 4
+	InvalidMoveIllegal     InvalidMoveReason = 5
+)
+
+// ActionRejectReason is sent with ActionRejected after the game id and the rejected command
+type ActionRejectReason uint8
+
+const (
+	ActionRejectMalformed      ActionRejectReason = 1
+	ActionRejectNoGame         ActionRejectReason = 2
+	ActionRejectNotInGame      ActionRejectReason = 3
+	ActionRejectNoDrawOffer    ActionRejectReason = 4  // accept/decline without an offer from the opponent
+	ActionRejectOfferPending   ActionRejectReason = 5  // the sender's own draw offer or takeback request is still open
+	ActionRejectNoClaim        ActionRejectReason = 6  // neither threefold repetition nor the fifty-move rule applies
+	ActionRejectTooLate        ActionRejectReason = 7  // abort after the sender already moved
+	ActionRejectNoTakebacks    ActionRejectReason = 8  // the mode doesn't allow takebacks
+	ActionRejectNothingToUndo  ActionRejectReason = 9  // the sender hasn't moved yet
+	ActionRejectNoTakeback     ActionRejectReason = 10 // accept/decline without a request from the opponent
+	ActionRejectAlreadyPlaying ActionRejectReason = 11 // a computer game while the sender already plays one
+)
+
 var ClientCmds = struct {
 	Pong             MsgType
 	Auth             MsgType
@@ -44,6 +88,15 @@ var ClientCmds = struct {
 	DeclinedGame     MsgType
 	CloseSocket      MsgType
 	MovePiece        MsgType
+	Resign           MsgType
+	OfferDraw        MsgType
+	AcceptDraw       MsgType
+	DeclineDraw      MsgType
+	ClaimDraw        MsgType
+	Abort            MsgType
+	RequestTakeback  MsgType
+	AcceptTakeback   MsgType
+	DeclineTakeback  MsgType
 }{
 	Pong:             1,
 	Auth:             2,
@@ -51,6 +104,15 @@ var ClientCmds = struct {
 	AcceptedGame:     4,
 	DeclinedGame:     5,
 	MovePiece:        10,
+	Resign:           11,
+	OfferDraw:        12,
+	AcceptDraw:       13,
+	DeclineDraw:      14,
+	ClaimDraw:        15,
+	Abort:            16,
+	RequestTakeback:  17,
+	AcceptTakeback:   18,
+	DeclineTakeback:  19,
 	CloseSocket:      61500,
 }
 
@@ -61,35 +123,48 @@ func handleMessage(msgType MsgType, payload []byte, client *Client) {
 	case ClientCmds.SearchingForGame:
 		gameMode := binary.BigEndian.Uint16(payload)
 		logger.Log.Info().Uint32("clientId", client.UserID).Uint16("gameMode", gameMode).Msg("Client wants to find game")
+		if GameMode(gameMode) == ModeVsComputer {
+			// no queue needed, an optional third byte picks the bot level
+			level := 1
+			if len(payload) > 2 {
+				level = int(payload[2])
+			}
+			StartComputerGame(client, level)
+			return
+		}
 		EnqueuePlayerForMode(client, gameMode)
 	case ClientCmds.CloseSocket:
 		logger.Log.Info().Uint32("clientId", client.UserID).Msg("Client wants to close socket")
 	case ClientCmds.MovePiece:
-		invalid := func() {
-			client.WriteMsg(ServerCmds.InvalidMove, nil)
-		}
 		logger.Log.Info().Uint32("clientId", client.UserID).Msg("Received move")
 		if len(payload) < 3 {
 			logger.Log.Warn().Uint32("clientId", client.UserID).Msg("Invalid move payload length")
-			invalid()
+			sendInvalidMove(client, 0, InvalidMoveMalformed)
 			return
 		}
 
 		ints, err := bh.Unpack(payload, []bh.FieldType{bh.Int8, bh.Int8, bh.Int8, bh.Uint32})
 		if err != nil {
 			logger.Log.Warn().Uint32("clientId", client.UserID).Err(err).Msg("Can't unpack move")
-			invalid()
+			sendInvalidMove(client, 0, InvalidMoveMalformed)
 			return
 		}
-		from := ints[0].(int8)
+		from := ints[0].(int8) // drops send chess.DropOffset + piece type (0 pawn .. 4 queen)
 		to := ints[1].(int8)
 		promoteTo := ints[2].(int8)
 
-		game, ok := keeper.GetGame(ints[3].(uint32))
+		gameID := ints[3].(uint32)
+		game, ok := keeper.GetGame(gameID)
 
 		if game == nil || !ok {
-			logger.Log.Warn().Uint32("clientId", client.UserID).Uint32("gameId", ints[3].(uint32)).Msg("Couldnt find active game with given id")
-			invalid()
+			logger.Log.Warn().Uint32("clientId", client.UserID).Uint32("gameId", gameID).Msg("Couldnt find active game with given id")
+			sendInvalidMove(client, gameID, InvalidMoveNoGame)
+			return
+		}
+		// whose turn it is gets checked in the game loop, which owns the board
+		if _, ok := game.PlayerColor(client); !ok {
+			logger.Log.Warn().Uint32("clientId", client.UserID).Uint32("gameId", gameID).Msg("Move for a game the client doesn't play in")
+			sendInvalidMove(client, gameID, InvalidMoveNotInGame)
 			return
 		}
 
@@ -100,12 +175,60 @@ func handleMessage(msgType MsgType, payload []byte, client *Client) {
 			Player:    client,
 		}
 		logger.Log.Info().Uint32("gameId", game.ID).Int("from", int(from)).Int("to", int(to)).Int("promoteTo", int(promoteTo)).Uint32("playerId", client.UserID).Msg("Sending move to game")
-		game.MoveChannel <- move
+		select {
+		case game.MoveChannel <- move:
+		case <-game.Done():
+			sendInvalidMove(client, gameID, InvalidMoveNoGame)
+		}
+	case ClientCmds.Resign, ClientCmds.OfferDraw, ClientCmds.AcceptDraw,
+		ClientCmds.DeclineDraw, ClientCmds.ClaimDraw, ClientCmds.Abort,
+		ClientCmds.RequestTakeback, ClientCmds.AcceptTakeback, ClientCmds.DeclineTakeback:
+		// payload is just the game id
+		ints, err := bh.Unpack(payload, []bh.FieldType{bh.Uint32})
+		if err != nil {
+			logger.Log.Warn().Uint32("clientId", client.UserID).Err(err).Uint16("cmd", uint16(msgType)).Msg("Can't unpack game action")
+			sendActionRejected(client, 0, msgType, ActionRejectMalformed)
+			return
+		}
+		gameID := ints[0].(uint32)
+		game, ok := keeper.GetGame(gameID)
+		if game == nil || !ok {
+			sendActionRejected(client, gameID, msgType, ActionRejectNoGame)
+			return
+		}
+		if _, ok := game.PlayerColor(client); !ok {
+			sendActionRejected(client, gameID, msgType, ActionRejectNotInGame)
+			return
+		}
+		logger.Log.Info().Uint32("gameId", gameID).Uint32("playerId", client.UserID).Uint16("cmd", uint16(msgType)).Msg("Sending action to game")
+		select {
+		case game.ActionChannel <- PlayerAction{Cmd: msgType, Player: client}:
+		case <-game.Done():
+			sendActionRejected(client, gameID, msgType, ActionRejectNoGame)
+		}
 
 	default:
 	}
 }
 
+func sendInvalidMove(client *Client, gameID uint32, reason InvalidMoveReason) {
+	payload, err := bh.Pack([]bh.FieldType{bh.Uint32, bh.Uint8}, []any{gameID, uint8(reason)})
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", gameID).Msg("couldnt pack invalid move")
+		return
+	}
+	client.WriteMsg(ServerCmds.InvalidMove, payload)
+}
+
+func sendActionRejected(client *Client, gameID uint32, cmd MsgType, reason ActionRejectReason) {
+	payload, err := bh.Pack([]bh.FieldType{bh.Uint32, bh.Uint16, bh.Uint8}, []any{gameID, uint16(cmd), uint8(reason)})
+	if err != nil {
+		logger.Log.Warn().Err(err).Uint32("gameId", gameID).Msg("couldnt pack action rejected")
+		return
+	}
+	client.WriteMsg(ServerCmds.ActionRejected, payload)
+}
+
 func WriteMsgToSingleConn(conn net.Conn, msgType MsgType, payload []byte) error {
 	full := make([]byte, 2+len(payload))
 
@@ -103,13 +103,17 @@ func handleConn(conn net.Conn) {
 			userID = uid
 
 			// Add or get shared Client for this user
-			client = GetClientOrCreate(userID)
+			var reattached bool
+			client, reattached = GetClientOrCreate(userID)
 			client.AddConn(connID, conn)
 
 			payload, _ := bh.Pack([]bh.FieldType{bh.Uint32}, []any{client.UserID})
 
 			// Send auth success
 			WriteMsgToSingleConn(conn, ServerCmds.ClientAuthenticated, payload)
+			if reattached {
+				client.ResumeGames()
+			}
 
 			PutBuffer(bufPtr)
 			continue
@@ -132,10 +136,8 @@ func handleConn(conn net.Conn) {
 
 func closeConn(client *Client, connID uint64) { // Connection closed, remove this connection from the client's map
 	if client != nil {
+		// the last conn takes the client with it inside RemoveConn
 		remainingConns := client.RemoveConn(connID)
-		if remainingConns <= 0 {
-			RemoveClient(client.UserID)
-		}
 		logger.Log.Info().Uint32("clientId", client.UserID).Int("remainingConns", remainingConns).Msg("Connection closed, client has remaining connections")
 	}
 }
@@ -0,0 +1,587 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gobwas/ws/wsutil"
+	"github.com/zefir/szaszki-go-backend/config"
+	bh "github.com/zefir/szaszki-go-backend/internal/binaryHelpers"
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+// testGrace is the reconnect grace period the session tests run with
+const testGrace = 200 * time.Millisecond
+
+func TestMain(m *testing.M) {
+	InitGameKeeper()
+	config.AppConfig.RECONNECT_GRACE = testGrace
+	os.Exit(m.Run())
+}
+
+// testPlayer is a client whose connections are read by the test
+type testPlayer struct {
+	*Client
+	msgs chan testMsg
+}
+
+type testMsg struct {
+	msgType MsgType
+	payload []byte
+}
+
+// the game keeper is shared by all tests, so every player gets a user id of its own
+var lastTestUser atomic.Uint32
+
+func newTestPlayer(t *testing.T) *testPlayer {
+	p := &testPlayer{
+		Client: &Client{
+			UserID:        lastTestUser.Add(1),
+			Conns:         make(map[uint64]net.Conn),
+			QueuedInModes: make(map[uint16]bool),
+		},
+		msgs: make(chan testMsg, 256),
+	}
+	p.connect(t, 1)
+	return p
+}
+
+// connect adds a connection to the player's client, what the server writes to it ends up in msgs
+func (p *testPlayer) connect(t *testing.T, connID uint64) {
+	server, conn := net.Pipe()
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		for {
+			data, _, err := wsutil.ReadServerData(conn)
+			if err != nil {
+				return
+			}
+			p.msgs <- testMsg{msgType: MsgType(binary.BigEndian.Uint16(data)), payload: data[2:]}
+		}
+	}()
+	p.AddConn(connID, server)
+}
+
+// expect skips other messages until one of msgType arrives and unpacks the start of its payload
+func (p *testPlayer) expect(t *testing.T, msgType MsgType, format ...bh.FieldType) []any {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-p.msgs:
+			if msg.msgType != msgType {
+				continue
+			}
+			values, err := bh.Unpack(msg.payload, format)
+			if err != nil {
+				t.Fatalf("player %d, message %d: %v", p.UserID, msgType, err)
+			}
+			return values
+		case <-timeout:
+			t.Fatalf("player %d got no message %d", p.UserID, msgType)
+			return nil
+		}
+	}
+}
+
+func (p *testPlayer) expectInvalidMove(t *testing.T, g *GameSession, reason InvalidMoveReason) {
+	t.Helper()
+	values := p.expect(t, ServerCmds.InvalidMove, bh.Uint32, bh.Uint8)
+	if values[0].(uint32) != g.ID || InvalidMoveReason(values[1].(uint8)) != reason {
+		t.Fatalf("player %d: invalid move %v, want game %d reason %d", p.UserID, values, g.ID, reason)
+	}
+}
+
+func (p *testPlayer) expectRejected(t *testing.T, g *GameSession, cmd MsgType, reason ActionRejectReason) {
+	t.Helper()
+	values := p.expect(t, ServerCmds.ActionRejected, bh.Uint32, bh.Uint16, bh.Uint8)
+	if values[0].(uint32) != g.ID || MsgType(values[1].(uint16)) != cmd || ActionRejectReason(values[2].(uint8)) != reason {
+		t.Fatalf("player %d: rejection %v, want game %d cmd %d reason %d", p.UserID, values, g.ID, cmd, reason)
+	}
+}
+
+func (p *testPlayer) expectGameOver(t *testing.T, g *GameSession, result chess.Result, reason chess.Termination) {
+	t.Helper()
+	values := p.expect(t, ServerCmds.GameOver, bh.Uint32, bh.Uint8, bh.Uint8)
+	if values[0].(uint32) != g.ID || chess.Result(values[1].(uint8)) != result || chess.Termination(values[2].(uint8)) != reason {
+		t.Fatalf("player %d: game over %v, want game %d %s by %s", p.UserID, values, g.ID, result, reason)
+	}
+}
+
+// startGame runs a custom game, which has no clock and allows takebacks
+func startGame(t *testing.T, white, black *Client) *GameSession {
+	return startModeGame(t, ModeCustom, white, black)
+}
+
+// startModeGame runs a game of mode, whatever is still running when the test ends is resigned
+func startModeGame(t *testing.T, mode GameMode, white, black *Client) *GameSession {
+	g := GetGameKeeper().CreateGame([]*Client{white, black}, uint16(mode))
+	t.Cleanup(func() {
+		select {
+		case g.ActionChannel <- PlayerAction{Cmd: ClientCmds.Resign, Player: white}:
+		case <-g.Done():
+		}
+		waitDone(t, g)
+	})
+	return g
+}
+
+func waitDone(t *testing.T, g *GameSession) {
+	t.Helper()
+	select {
+	case <-g.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("game %d still running", g.ID)
+	}
+}
+
+// play sends a move in UCI notation for player
+func play(t *testing.T, g *GameSession, player *Client, move string) {
+	t.Helper()
+	from, err := chess.ParseSquare(move[0:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := chess.ParseSquare(move[2:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.MoveChannel <- PlayerMove{From: from, To: to, Player: player}
+}
+
+// playMoves plays the moves in turn and waits until the last one was broadcast
+func playMoves(t *testing.T, g *GameSession, white, black *testPlayer, moves ...string) {
+	t.Helper()
+	for i, m := range moves {
+		player := white
+		if i%2 == 1 {
+			player = black
+		}
+		play(t, g, player.Client, m)
+		white.expect(t, ServerCmds.MoveHappend)
+		black.expect(t, ServerCmds.MoveHappend)
+	}
+}
+
+// board reads the game's position, only call it after a message showed Run is done changing it
+func board(g *GameSession) chess.Board {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.Board
+}
+
+func TestMoveOwnershipAndTurn(t *testing.T) {
+	white, black, outsider := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+	white.expect(t, ServerCmds.GameStarted)
+	start := board(g)
+
+	play(t, g, outsider.Client, "e2e4")
+	outsider.expectInvalidMove(t, g, InvalidMoveNotYourTurn)
+	play(t, g, black.Client, "e7e5")
+	black.expectInvalidMove(t, g, InvalidMoveNotYourTurn)
+	if board(g) != start {
+		t.Fatal("board changed by moves from the wrong players")
+	}
+
+	playMoves(t, g, white, black, "e2e4")
+	afterE4 := board(g)
+
+	play(t, g, white.Client, "d2d4")
+	white.expectInvalidMove(t, g, InvalidMoveNotYourTurn)
+	play(t, g, outsider.Client, "e7e5")
+	outsider.expectInvalidMove(t, g, InvalidMoveNotYourTurn)
+	if board(g) != afterE4 {
+		t.Fatal("board changed by moves from the wrong players")
+	}
+
+	play(t, g, black.Client, "e7e4")
+	black.expectInvalidMove(t, g, InvalidMoveIllegal)
+	play(t, g, black.Client, "e7e5")
+	white.expect(t, ServerCmds.MoveHappend)
+}
+
+func TestNotifyPartnerWaitsForRoom(t *testing.T) {
+	keeper := GetGameKeeper()
+	keeper.mu.Lock()
+	first := keeper.newSession([]*Client{newTestPlayer(t).Client, newTestPlayer(t).Client}, uint16(ModeBughouse))
+	second := keeper.newSession([]*Client{newTestPlayer(t).Client, newTestPlayer(t).Client}, uint16(ModeBughouse))
+	keeper.mu.Unlock()
+	first.Partner, second.Partner = second, first
+	t.Cleanup(func() {
+		keeper.RemoveGame(first.ID)
+		keeper.RemoveGame(second.ID)
+	})
+
+	for len(second.PartnerChannel) < cap(second.PartnerChannel) {
+		second.PartnerChannel <- partnerEvent{Piece: chess.Pawn}
+	}
+	sent := make(chan struct{})
+	go func() {
+		first.notifyPartner(partnerEvent{Piece: chess.Queen})
+		close(sent)
+	}()
+	// the partner is busy notifying back, first must keep what it hears meanwhile
+	first.PartnerChannel <- partnerEvent{Piece: chess.Knight}
+
+	select {
+	case <-sent:
+		t.Fatal("notifyPartner returned while the partner's channel was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := 0; i < cap(second.PartnerChannel); i++ {
+		if event := <-second.PartnerChannel; event.Piece != chess.Pawn {
+			t.Fatalf("event %d: piece %d, want the pawns first", i, event.Piece)
+		}
+	}
+	<-sent
+	if event := <-second.PartnerChannel; event.Piece != chess.Queen {
+		t.Fatalf("got piece %d, want the queen", event.Piece)
+	}
+	if len(first.partnerBacklog)+len(first.PartnerChannel) != 1 {
+		t.Fatalf("%d events kept and %d queued, want the knight once", len(first.partnerBacklog), len(first.PartnerChannel))
+	}
+
+	// a finished partner doesn't take events anymore
+	for len(second.PartnerChannel) < cap(second.PartnerChannel) {
+		second.PartnerChannel <- partnerEvent{Piece: chess.Pawn}
+	}
+	close(second.done)
+	first.notifyPartner(partnerEvent{GameOver: true})
+}
+
+func TestBughouseCaptureReachesPartner(t *testing.T) {
+	var teams [2][2]*testPlayer
+	var clients [2][2]*Client
+	for i := range teams {
+		for j := range teams[i] {
+			teams[i][j] = newTestPlayer(t)
+			clients[i][j] = teams[i][j].Client
+		}
+	}
+	boards := GetGameKeeper().CreateBughouseGame(clients, uint16(ModeBughouse))
+	t.Cleanup(func() {
+		boards[0].ActionChannel <- PlayerAction{Cmd: ClientCmds.Resign, Player: clients[0][0]}
+		waitDone(t, boards[0])
+		waitDone(t, boards[1])
+	})
+
+	// teams[0][0] is white on the first board, teams[1][0] black
+	playMoves(t, boards[0], teams[0][0], teams[1][0], "e2e4", "d7d5", "e4d5")
+	// teams[0][1] plays black on the second board, the captured black pawn is theirs now
+	values := teams[0][1].expect(t, ServerCmds.PocketsChanged, bh.Uint32, bh.Uint8, bh.Uint8, bh.Uint8, bh.Uint8, bh.Uint8, bh.Uint8)
+	if values[0].(uint32) != boards[1].ID || values[1].(uint8) != 0 || values[6].(uint8) != 1 {
+		t.Fatalf("partner pockets %v, want a black pawn on game %d", values, boards[1].ID)
+	}
+}
+
+// scriptedEngine is a bot that plays the given moves in UCI notation
+type scriptedEngine struct {
+	moves []string
+	next  int
+}
+
+func (e *scriptedEngine) ChooseMove(history []chess.Board, moves []chess.Move) (chess.Move, error) {
+	if e.next == len(e.moves) {
+		return chess.Move{}, errors.New("script played out")
+	}
+	board := history[len(history)-1]
+	m, err := chess.ParseUCIMove(&board, e.moves[e.next])
+	e.next++
+	return m, err
+}
+
+func newTestBot(engine BotEngine) *Client {
+	return &Client{
+		Conns:         make(map[uint64]net.Conn),
+		QueuedInModes: make(map[uint16]bool),
+		Bot:           &Bot{Level: 1, Engine: engine},
+	}
+}
+
+func TestResign(t *testing.T) {
+	white, black, outsider := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.Resign, Player: outsider.Client}
+	outsider.expectRejected(t, g, ClientCmds.Resign, ActionRejectNotInGame)
+
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.Resign, Player: black.Client}
+	white.expectGameOver(t, g, chess.ResultWhiteWins, chess.TerminationResignation)
+	black.expectGameOver(t, g, chess.ResultWhiteWins, chess.TerminationResignation)
+}
+
+func TestDrawOffer(t *testing.T) {
+	white, black := newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+	act := func(p *testPlayer, cmd MsgType) {
+		g.ActionChannel <- PlayerAction{Cmd: cmd, Player: p.Client}
+	}
+
+	act(black, ClientCmds.AcceptDraw)
+	black.expectRejected(t, g, ClientCmds.AcceptDraw, ActionRejectNoDrawOffer)
+
+	act(white, ClientCmds.OfferDraw)
+	black.expect(t, ServerCmds.DrawOffered)
+	act(white, ClientCmds.OfferDraw)
+	white.expectRejected(t, g, ClientCmds.OfferDraw, ActionRejectOfferPending)
+	act(white, ClientCmds.AcceptDraw)
+	white.expectRejected(t, g, ClientCmds.AcceptDraw, ActionRejectNoDrawOffer)
+	act(black, ClientCmds.DeclineDraw)
+	white.expect(t, ServerCmds.DrawDeclined)
+	act(black, ClientCmds.DeclineDraw)
+	black.expectRejected(t, g, ClientCmds.DeclineDraw, ActionRejectNoDrawOffer)
+
+	// moving instead of answering lets the offer lapse
+	act(white, ClientCmds.OfferDraw)
+	black.expect(t, ServerCmds.DrawOffered)
+	playMoves(t, g, white, black, "e2e4", "e7e5")
+	act(black, ClientCmds.AcceptDraw)
+	black.expectRejected(t, g, ClientCmds.AcceptDraw, ActionRejectNoDrawOffer)
+
+	// offering while the opponent's offer is open agrees to it
+	act(black, ClientCmds.OfferDraw)
+	white.expect(t, ServerCmds.DrawOffered)
+	act(white, ClientCmds.OfferDraw)
+	white.expectGameOver(t, g, chess.ResultDraw, chess.TerminationAgreement)
+	black.expectGameOver(t, g, chess.ResultDraw, chess.TerminationAgreement)
+}
+
+func TestDrawOfferToComputer(t *testing.T) {
+	white := newTestPlayer(t)
+	g := startModeGame(t, ModeVsComputer, white.Client, newTestBot(&scriptedEngine{moves: []string{"e7e5"}}))
+
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.OfferDraw, Player: white.Client}
+	white.expect(t, ServerCmds.DrawDeclined)
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.AcceptDraw, Player: white.Client}
+	white.expectRejected(t, g, ClientCmds.AcceptDraw, ActionRejectNoDrawOffer)
+}
+
+// knightShuffle repeats the starting position twice more
+var knightShuffle = []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"}
+
+func TestClaimDraw(t *testing.T) {
+	white, black := newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.ClaimDraw, Player: white.Client}
+	white.expectRejected(t, g, ClientCmds.ClaimDraw, ActionRejectNoClaim)
+
+	// the repetition doesn't end the game on its own
+	playMoves(t, g, white, black, knightShuffle...)
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.ClaimDraw, Player: white.Client}
+	white.expectGameOver(t, g, chess.ResultDraw, chess.TerminationThreefoldRepetition)
+	black.expectGameOver(t, g, chess.ResultDraw, chess.TerminationThreefoldRepetition)
+}
+
+func TestComputerGameEndsOnRepetition(t *testing.T) {
+	white := newTestPlayer(t)
+	var botMoves []string
+	for i := 1; i < len(knightShuffle); i += 2 {
+		botMoves = append(botMoves, knightShuffle[i])
+	}
+	bot := newTestBot(&scriptedEngine{moves: botMoves})
+	g := startModeGame(t, ModeVsComputer, white.Client, bot)
+
+	for i := 0; i < len(knightShuffle); i += 2 {
+		play(t, g, white.Client, knightShuffle[i])
+		white.expect(t, ServerCmds.MoveHappend)
+		white.expect(t, ServerCmds.MoveHappend)
+	}
+	white.expectGameOver(t, g, chess.ResultDraw, chess.TerminationThreefoldRepetition)
+}
+
+func TestAbort(t *testing.T) {
+	white, black := newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+
+	playMoves(t, g, white, black, "e2e4")
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.Abort, Player: white.Client}
+	white.expectRejected(t, g, ClientCmds.Abort, ActionRejectTooLate)
+
+	// black hasn't moved yet
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.Abort, Player: black.Client}
+	white.expectGameOver(t, g, chess.ResultNone, chess.TerminationAborted)
+	black.expectGameOver(t, g, chess.ResultNone, chess.TerminationAborted)
+}
+
+func TestTakebackConsent(t *testing.T) {
+	white, black := newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+	act := func(p *testPlayer, cmd MsgType) {
+		g.ActionChannel <- PlayerAction{Cmd: cmd, Player: p.Client}
+	}
+	white.expect(t, ServerCmds.GameStarted)
+	start := board(g)
+
+	act(white, ClientCmds.RequestTakeback)
+	white.expectRejected(t, g, ClientCmds.RequestTakeback, ActionRejectNothingToUndo)
+
+	playMoves(t, g, white, black, "e2e4", "e7e5")
+	act(white, ClientCmds.RequestTakeback)
+	black.expect(t, ServerCmds.TakebackRequested)
+	act(white, ClientCmds.RequestTakeback)
+	white.expectRejected(t, g, ClientCmds.RequestTakeback, ActionRejectOfferPending)
+	act(white, ClientCmds.AcceptTakeback)
+	white.expectRejected(t, g, ClientCmds.AcceptTakeback, ActionRejectNoTakeback)
+	act(black, ClientCmds.DeclineTakeback)
+	white.expect(t, ServerCmds.TakebackDeclined)
+
+	// white's move and black's reply both go, white is to move again
+	act(white, ClientCmds.RequestTakeback)
+	black.expect(t, ServerCmds.TakebackRequested)
+	act(black, ClientCmds.AcceptTakeback)
+	values := white.expect(t, ServerCmds.TakebackDone, bh.Uint32, bh.Uint8)
+	if values[1].(uint8) != 2 {
+		t.Fatalf("took back %d plies, want 2", values[1])
+	}
+	if board(g) != start {
+		t.Fatal("takeback didn't restore the starting position")
+	}
+
+	// a request lapses once the opponent moves
+	playMoves(t, g, white, black, "d2d4")
+	act(white, ClientCmds.RequestTakeback)
+	black.expect(t, ServerCmds.TakebackRequested)
+	play(t, g, black.Client, "d7d5")
+	white.expect(t, ServerCmds.MoveHappend)
+	act(black, ClientCmds.AcceptTakeback)
+	black.expectRejected(t, g, ClientCmds.AcceptTakeback, ActionRejectNoTakeback)
+}
+
+func TestTakebackNotAllowed(t *testing.T) {
+	white, black := newTestPlayer(t), newTestPlayer(t)
+	g := startModeGame(t, ModeRanked, white.Client, black.Client)
+
+	playMoves(t, g, white, black, "e2e4")
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.RequestTakeback, Player: white.Client}
+	white.expectRejected(t, g, ClientCmds.RequestTakeback, ActionRejectNoTakebacks)
+}
+
+// gatedEngine plays its script, each move only once the test lets it through
+type gatedEngine struct {
+	scriptedEngine
+	gate chan struct{}
+}
+
+func (e *gatedEngine) ChooseMove(history []chess.Board, moves []chess.Move) (chess.Move, error) {
+	<-e.gate
+	return e.scriptedEngine.ChooseMove(history, moves)
+}
+
+func TestStaleBotMoveDropped(t *testing.T) {
+	white := newTestPlayer(t)
+	engine := &gatedEngine{scriptedEngine: scriptedEngine{moves: []string{"e7e5", "d7d5"}}, gate: make(chan struct{})}
+	g := startModeGame(t, ModeVsComputer, white.Client, newTestBot(engine))
+
+	// the bot is still thinking about 1. e4 when white takes it back and plays 1. d4 instead
+	play(t, g, white.Client, "e2e4")
+	white.expect(t, ServerCmds.MoveHappend)
+	g.ActionChannel <- PlayerAction{Cmd: ClientCmds.RequestTakeback, Player: white.Client}
+	white.expect(t, ServerCmds.TakebackDone)
+	play(t, g, white.Client, "d2d4")
+	white.expect(t, ServerCmds.MoveHappend)
+
+	// 1... e5 answers the old position, only 1... d5 may be played
+	engine.gate <- struct{}{}
+	engine.gate <- struct{}{}
+	white.expect(t, ServerCmds.MoveHappend)
+	b := board(g)
+	e7, _ := chess.ParseSquare("e7")
+	d5, _ := chess.ParseSquare("d5")
+	if chess.GetPieceType(&b, e7, chess.Black) != chess.Pawn || chess.GetPieceType(&b, d5, chess.Black) != chess.Pawn {
+		t.Fatalf("got %s, want 1. d4 d5", b.FEN())
+	}
+}
+
+// forgetClient drops what GetClientOrCreate registered for the player
+func forgetClient(t *testing.T, p *testPlayer) {
+	t.Cleanup(func() {
+		clientsMu.Lock()
+		delete(clients, p.UserID)
+		clientsMu.Unlock()
+	})
+}
+
+func TestReconnectWithinGrace(t *testing.T) {
+	white, black := newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+	white.expect(t, ServerCmds.GameStarted)
+	forgetClient(t, white)
+
+	white.RemoveConn(1)
+	client, reattached := GetClientOrCreate(white.UserID)
+	if client != white.Client || !reattached {
+		t.Fatal("reconnecting player didn't get the client their game holds")
+	}
+	white.connect(t, 2)
+	client.ResumeGames()
+	white.expect(t, ServerCmds.GameStarted)
+	white.expect(t, ServerCmds.GameState)
+
+	// a second tab joins the same client, there's nothing to resume
+	if again, reattached := GetClientOrCreate(white.UserID); again != client || reattached {
+		t.Fatal("connected player's client was reattached again")
+	}
+	// removal of the old client is a no-op now that it's back in use
+	RemoveClient(client)
+	if current, ok := GetClient(white.UserID); !ok || current != client {
+		t.Fatal("reattached client was removed")
+	}
+
+	time.Sleep(2 * testGrace)
+	playMoves(t, g, white, black, "e2e4")
+}
+
+func TestGraceExpires(t *testing.T) {
+	white, black := newTestPlayer(t), newTestPlayer(t)
+	g := startGame(t, white.Client, black.Client)
+	playMoves(t, g, white, black, "e2e4")
+
+	disconnected := time.Now()
+	black.RemoveConn(1)
+	white.expectGameOver(t, g, chess.ResultWhiteWins, chess.TerminationAbandoned)
+	if waited := time.Since(disconnected); waited < testGrace {
+		t.Fatalf("game ended %v after the disconnect, before the %v grace period", waited, testGrace)
+	}
+
+	// once the game is gone there's nothing to reattach to
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(time.Millisecond) {
+		if _, ok := GetGameKeeper().GetGame(g.ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("finished game still kept")
+		}
+	}
+	forgetClient(t, black)
+	if _, reattached := GetClientOrCreate(black.UserID); reattached {
+		t.Fatal("client reattached to a finished game")
+	}
+}
+
+// illegalEngine always answers with a move that isn't legal
+type illegalEngine struct{}
+
+func (illegalEngine) ChooseMove(history []chess.Board, moves []chess.Move) (chess.Move, error) {
+	e7, _ := chess.ParseSquare("e7")
+	e4, _ := chess.ParseSquare("e4")
+	return chess.Move{From: e7, To: e4}, nil
+}
+
+func TestIllegalBotMoveFallsBack(t *testing.T) {
+	white := newTestPlayer(t)
+	g := startModeGame(t, ModeVsComputer, white.Client, newTestBot(illegalEngine{}))
+
+	play(t, g, white.Client, "e2e4")
+	white.expect(t, ServerCmds.MoveHappend)
+	white.expect(t, ServerCmds.MoveHappend)
+	if b := board(g); b.ActiveColor() != chess.White {
+		t.Fatalf("got %s, want the bot to have answered", b.FEN())
+	}
+}
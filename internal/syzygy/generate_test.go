@@ -0,0 +1,651 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+// The tables in testdata are built here by retrograde analysis and compressed like the
+// syzygy generator does: symbol pairs, canonical huffman codes and dtz maps.
+// go test -run TestGenerateTables -update
+var update = flag.Bool("update", false, "regenerate the tables in testdata")
+
+// solution holds a king and one piece against a bare king, by solutionIndex
+type solution struct {
+	wdl []int8  // for the side to move
+	dtz []int16 // signed like ProbeDTZ
+}
+
+func solutionIndex(blackToMove bool, wk, sq, bk int) int {
+	return ((btoi(blackToMove)*64+wk)*64+sq)*64 + bk
+}
+
+type edge struct {
+	child   int32 // -1 when the move leaves the material, value is set then
+	value   int8
+	zeroing bool
+}
+
+// solve works out KXvK for the white piece, promotions look up the solved tables
+func solve(t *testing.T, piece int, promotions map[int]*solution) *solution {
+	const size = 2 * 64 * 64 * 64
+	legal := make([]bool, size)
+	offsets := make([]int32, size+1)
+	var edges []edge
+
+	for idx := 0; idx < size; idx++ {
+		offsets[idx] = int32(len(edges))
+		blackToMove, wk, sq, bk := idx>>18 == 1, idx>>12&63, idx>>6&63, idx&63
+		board, ok := solverBoard(piece, blackToMove, wk, sq, bk)
+		if !ok {
+			continue
+		}
+		legal[idx] = true
+		for _, m := range chess.GenerateLegalMoves(&board) {
+			child := board
+			undo := chess.MakeMove(&child, m.From, m.To, m.Promotion)
+			e := edge{zeroing: child.HalfmoveClock == 0}
+			switch {
+			case undo.CapturedPiece != -1:
+				e.child = -1 // bare kings
+			case m.Promotion != 0:
+				e.child = -1
+				e.value = promotions[promotionPiece(m.Promotion)].wdl[childIndex(&child, piece)]
+			default:
+				e.child = int32(childIndex(&child, piece))
+			}
+			edges = append(edges, e)
+		}
+	}
+	offsets[size] = int32(len(edges))
+
+	s := &solution{wdl: make([]int8, size), dtz: make([]int16, size)}
+	known := make([]bool, size)
+	value := func(e edge) (int8, bool) {
+		if e.child < 0 {
+			return e.value, true
+		}
+		return s.wdl[e.child], known[e.child]
+	}
+	for changed := true; changed; {
+		changed = false
+		for idx := range size {
+			if !legal[idx] || known[idx] {
+				continue
+			}
+			moves := edges[offsets[idx]:offsets[idx+1]]
+			if len(moves) == 0 {
+				board, _ := solverBoard(piece, idx>>18 == 1, idx>>12&63, idx>>6&63, idx&63)
+				s.wdl[idx], known[idx] = 0, true
+				if board.InCheck() {
+					s.wdl[idx] = -2
+				}
+				changed = true
+				continue
+			}
+			allWins := true
+			for _, e := range moves {
+				v, ok := value(e)
+				if ok && v == -2 {
+					s.wdl[idx], known[idx], changed = 2, true, true
+					break
+				}
+				allWins = allWins && ok && v == 2
+			}
+			if !known[idx] && allWins {
+				s.wdl[idx], known[idx], changed = -2, true, true
+			}
+		}
+	}
+
+	// distances grow one ply at a time: a win takes the quickest lost child,
+	// a loss waits until every child has its distance
+	dtzKnown := make([]bool, size)
+	remaining := 0
+	for idx := range size {
+		if legal[idx] && s.wdl[idx] != 0 {
+			remaining++
+		}
+	}
+	for ply := int16(1); remaining > 0; ply++ {
+		if ply > 100 {
+			t.Fatalf("K%cvK: %d positions past the fifty-move rule", "PNBRQK"[piece], remaining)
+		}
+		var assigned []int
+		for idx := range size {
+			if !legal[idx] || s.wdl[idx] == 0 || dtzKnown[idx] {
+				continue
+			}
+			moves := edges[offsets[idx]:offsets[idx+1]]
+			if s.wdl[idx] > 0 {
+				for _, e := range moves {
+					v, _ := value(e)
+					mates := e.child >= 0 && offsets[e.child] == offsets[e.child+1]
+					if v != -2 {
+						continue
+					}
+					if ply == 1 && (e.zeroing || mates) ||
+						!e.zeroing && e.child >= 0 && dtzKnown[e.child] && -s.dtz[e.child] == ply-1 {
+						assigned = append(assigned, idx)
+						break
+					}
+				}
+				continue
+			}
+			ready := true
+			for _, e := range moves {
+				if !e.zeroing && (e.child < 0 || !dtzKnown[e.child] || s.dtz[e.child] >= ply) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				assigned = append(assigned, idx)
+			}
+		}
+		for _, idx := range assigned {
+			s.dtz[idx] = ply * int16(s.wdl[idx]/2)
+			dtzKnown[idx] = true
+		}
+		remaining -= len(assigned)
+	}
+	return s
+}
+
+func promotionPiece(promo int8) int {
+	return map[int8]int{chess.PromoteQueen: chess.Queen, chess.PromoteRook: chess.Rook,
+		chess.PromoteBishop: chess.Bishop, chess.PromoteKnight: chess.Knight}[promo]
+}
+
+// childIndex finds the squares after a quiet move or a promotion to piece's table
+func childIndex(board *chess.Board, piece int) int {
+	wk := chess.PopLSB(&board.Kings[chess.White])
+	bk := chess.PopLSB(&board.Kings[chess.Black])
+	others := board.Occupied[chess.White] &^ (chess.Bitboard(1) << wk)
+	return solutionIndex(board.ActiveColor() == chess.Black, wk, chess.PopLSB(&others), bk)
+}
+
+// solverBoard sets up the position, ok is false for illegal ones
+func solverBoard(piece int, blackToMove bool, wk, sq, bk int) (chess.Board, bool) {
+	if wk == sq || wk == bk || sq == bk || kingDistance(wk, bk) <= 1 {
+		return chess.Board{}, false
+	}
+	if piece == chess.Pawn && (sq < 8 || sq >= 56) {
+		return chess.Board{}, false
+	}
+	var squares [64]byte
+	squares[wk], squares[sq], squares[bk] = 'K', "PNBRQK"[piece], 'k'
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			c := squares[rank*8+file]
+			if c == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(c)
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	side := "w"
+	if blackToMove {
+		side = "b"
+	}
+	board, err := chess.ParseFEN(sb.String() + " " + side + " - - 0 1")
+	if err != nil {
+		return chess.Board{}, false
+	}
+	// the side that just moved can't be in check
+	king := board.Kings[1-board.ActiveColor()]
+	if chess.IsSquareAttacked(chess.PopLSB(&king), &board, board.ActiveColor()) {
+		return chess.Board{}, false
+	}
+	return board, true
+}
+
+// tableSpec lays out a KXvK table for the writer and the reader alike
+func tableSpec(piece int, dtz bool) *table {
+	name := fmt.Sprintf("K%cvK", "PNBRQK"[piece])
+	t := &table{name: name, dtz: dtz, num: 3, hasPawns: piece == chess.Pawn}
+	pieces := []int{chess.King + 1, piece + 1, chess.King + 1 + blackPiece}
+	files := 1
+	if t.hasPawns {
+		t.pawns[0] = 1
+		files = 4
+		pieces = []int{chess.Pawn + 1, chess.King + 1, chess.King + 1 + blackPiece}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < 2; s++ {
+			e := &t.files[f][s]
+			e.uniquePieces = !t.hasPawns
+			e.setup(t, pieces, 0, 0x0f, f)
+		}
+	}
+	return t
+}
+
+// tableValues places the solution at the reader's indexes: wdl+2, or for dtz the
+// position of the win's distance in moves in its file's map. Illegal positions hold draws
+func tableValues(piece int, s *solution, t *table) (values [4][2][]int, maps [4][4][]int) {
+	for f := range values {
+		for side := range values[f] {
+			values[f][side] = make([]int, t.files[f][side].size)
+			for i := range values[f][side] {
+				values[f][side][i] = 2
+				if t.dtz {
+					values[f][side][i] = -1
+				}
+			}
+		}
+	}
+	for idx := range s.wdl {
+		board, ok := solverBoard(piece, idx>>18 == 1, idx>>12&63, idx>>6&63, idx&63)
+		if !ok {
+			continue
+		}
+		file, side, i := t.index(&board, false)
+		if !t.dtz {
+			values[file][side][i] = int(s.wdl[idx]) + 2
+		} else if side == 0 && s.dtz[idx] > 0 {
+			values[file][0][i] = int(s.dtz[idx]-1) / 2
+		}
+	}
+	if !t.dtz {
+		return values, maps
+	}
+
+	// white to move never loses here, so only the wins' map has entries
+	for f := range values {
+		maps[f][0] = byFrequency(values[f][0])
+		position := make(map[int]int)
+		for i, v := range maps[f][0] {
+			position[v] = i
+		}
+		for i, v := range values[f][0] {
+			values[f][0][i] = position[v] // draws aren't in the map and read 0, they're never looked up
+		}
+	}
+	return values, maps
+}
+
+// byFrequency lists the distinct values that aren't negative, most common first
+func byFrequency(values []int) []int {
+	counts := make(map[int]int)
+	for _, v := range values {
+		if v >= 0 {
+			counts[v]++
+		}
+	}
+	distinct := make([]int, 0, len(counts))
+	for v := range counts {
+		distinct = append(distinct, v)
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		a, b := distinct[i], distinct[j]
+		return counts[a] > counts[b] || counts[a] == counts[b] && a < b
+	})
+	return distinct
+}
+
+const (
+	testBlockSize  = 6       // 64 byte blocks
+	testIdxBits    = 10      // an index entry per 1024 values
+	maxSymbols     = 0xfff   // symbols are numbered in 12 bits, 0xfff marks a leaf
+	maxSymbolSize  = 256     // values per symbol, readers count them in a byte
+	maxBlockValues = 1 << 15 // keeps the index's offsets in 16 bits, even past the last value
+	minPairCount   = 8       // a symbol costs 3 header bytes, rarer pairs don't pay for themselves
+)
+
+// pairsBlocks is what follows all the pairs headers of a table, one part after the other
+type pairsBlocks struct{ index, sizes, data []byte }
+
+// writeTable stores the values compressed the way the syzygy generator does it,
+// white to move only for dtz
+func writeTable(path string, t *table, values [4][2][]int, maps [4][4][]int) error {
+	files, sides := 1, 2
+	if t.hasPawns {
+		files = 4
+	}
+	if t.dtz {
+		sides = 1
+	}
+
+	buf := append([]byte(nil), wdlMagic...)
+	if t.dtz {
+		buf = append([]byte(nil), dtzMagic...)
+	}
+	buf = append(buf, byte(btoi(!t.dtz)|btoi(t.hasPawns)<<1))
+	for f := 0; f < files; f++ {
+		buf = append(buf, 0) // the leading group comes first in both sides' index
+		for _, piece := range t.files[f][0].pieces {
+			buf = append(buf, byte(piece|piece<<4))
+		}
+	}
+	if len(buf)&1 != 0 {
+		buf = append(buf, 0)
+	}
+
+	var blocks []pairsBlocks
+	var mapped [4]bool
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			var header []byte
+			var b pairsBlocks
+			switch {
+			case !t.dtz && allEqual(values[f][s]):
+				header = []byte{pairsSingleValue, byte(values[f][s][0])}
+			case t.dtz && len(maps[f][0]) == 0:
+				header = []byte{pairsSingleValue, 0} // no wins, nothing is ever looked up
+			default:
+				var err error
+				if header, b, err = encodePairs(values[f][s]); err != nil {
+					return fmt.Errorf("%s file %d side %d: %w", t.name, f, s, err)
+				}
+				if t.dtz {
+					header[0] = dtzFlagMapped
+					mapped[f] = true
+				}
+			}
+			buf = append(buf, header...)
+			blocks = append(blocks, b)
+		}
+	}
+	if t.dtz {
+		for f := 0; f < files; f++ {
+			if !mapped[f] {
+				continue
+			}
+			for _, m := range maps[f] {
+				buf = append(buf, byte(len(m)))
+				for _, v := range m {
+					buf = append(buf, byte(v))
+				}
+			}
+		}
+		if len(buf)&1 != 0 {
+			buf = append(buf, 0)
+		}
+	}
+	for _, b := range blocks {
+		buf = append(buf, b.index...)
+	}
+	for _, b := range blocks {
+		buf = append(buf, b.sizes...)
+	}
+	for _, b := range blocks {
+		if b.data == nil {
+			continue
+		}
+		for len(buf)&0x3f != 0 {
+			buf = append(buf, 0)
+		}
+		buf = append(buf, b.data...)
+	}
+	return os.WriteFile(path, buf, 0o644)
+}
+
+// encodePairs compresses values: the most frequent neighbouring symbols are merged into
+// pair symbols over and over, then the remaining sequence gets canonical huffman codes
+// packed into blocks. It returns the pairs header and what goes after all the headers.
+func encodePairs(values []int) (header []byte, b pairsBlocks, err error) {
+	// every distinct value starts out as a leaf symbol
+	var pairs [][2]int // left and right symbol, right is 0xfff for a leaf holding the value in left
+	var size []int     // values each symbol expands to
+	leaves := make(map[int]int)
+	seq := make([]int, len(values))
+	for i, v := range values {
+		sym, ok := leaves[v]
+		if !ok {
+			sym = len(pairs)
+			leaves[v] = sym
+			pairs = append(pairs, [2]int{v, 0xfff})
+			size = append(size, 1)
+		}
+		seq[i] = sym
+	}
+
+	for len(pairs) < maxSymbols {
+		counts := make(map[[2]int]int)
+		for i := 0; i+1 < len(seq); i++ {
+			if size[seq[i]]+size[seq[i+1]] <= maxSymbolSize {
+				counts[[2]int{seq[i], seq[i+1]}]++
+			}
+		}
+		best, bestCount := [2]int{}, 0
+		for pair, c := range counts {
+			if c > bestCount || c == bestCount && (pair[0] < best[0] || pair[0] == best[0] && pair[1] < best[1]) {
+				best, bestCount = pair, c
+			}
+		}
+		if bestCount < minPairCount {
+			break
+		}
+		sym := len(pairs)
+		pairs = append(pairs, best)
+		size = append(size, size[best[0]]+size[best[1]])
+		merged := seq[:0]
+		for i := 0; i < len(seq); i++ {
+			if i+1 < len(seq) && seq[i] == best[0] && seq[i+1] == best[1] {
+				merged = append(merged, sym)
+				i++
+			} else {
+				merged = append(merged, seq[i])
+			}
+		}
+		seq = merged
+	}
+
+	weights := make([]int, len(pairs))
+	used := 0
+	for _, sym := range seq {
+		if weights[sym] == 0 {
+			used++
+		}
+		weights[sym]++
+	}
+	if used < 2 {
+		// a code needs two symbols, the spare leaf never shows up in the data
+		pairs = append(pairs, [2]int{0, 0xfff})
+		size = append(size, 1)
+		weights = append(weights, 1)
+	}
+	codeLen := huffmanLengths(weights)
+
+	minLen, maxLen := 64, 0
+	for _, l := range codeLen {
+		if l > 0 {
+			minLen, maxLen = min(minLen, l), max(maxLen, l)
+		}
+	}
+	if maxLen > 32 {
+		// the reader refills its bit buffer 32 bits at a time
+		return nil, b, fmt.Errorf("code length %d over 32", maxLen)
+	}
+
+	// the reader numbers the coded symbols from the longest codes to the shortest,
+	// the ones only used inside pairs go last
+	var order []int
+	for l := maxLen; l >= minLen; l-- {
+		for sym, sl := range codeLen {
+			if sl == l {
+				order = append(order, sym)
+			}
+		}
+	}
+	for sym, l := range codeLen {
+		if l == 0 {
+			order = append(order, sym)
+		}
+	}
+	number := make([]int, len(pairs))
+	for n, sym := range order {
+		number[sym] = n
+	}
+
+	// lowest symbol number and first code of each length, minLen first
+	h := maxLen - minLen + 1
+	count := make([]int, h)
+	for _, l := range codeLen {
+		if l > 0 {
+			count[l-minLen]++
+		}
+	}
+	lowest := make([]int, h)
+	base := make([]uint64, h)
+	for i, n := h-1, 0; i >= 0; i-- {
+		lowest[i] = n
+		n += count[i]
+	}
+	for i := h - 2; i >= 0; i-- {
+		base[i] = (base[i+1] + uint64(count[i+1])) / 2
+	}
+
+	var realBlocks int
+	var starts, sizes []int // first value and number of values per block
+	bitPos, valuePos := 0, 0
+	for _, sym := range seq {
+		l := codeLen[sym]
+		if realBlocks == 0 || bitPos+l > 8<<testBlockSize || sizes[realBlocks-1]+size[sym] > maxBlockValues {
+			starts, sizes = append(starts, valuePos), append(sizes, 0)
+			b.data = append(b.data, make([]byte, 1<<testBlockSize)...)
+			realBlocks++
+			bitPos = 0
+		}
+		code := base[l-minLen] + uint64(number[sym]-lowest[l-minLen])
+		block := b.data[(realBlocks-1)<<testBlockSize:]
+		for bit := 0; bit < l; bit++ {
+			if code>>(l-1-bit)&1 != 0 {
+				block[(bitPos+bit)/8] |= 0x80 >> ((bitPos + bit) % 8)
+			}
+		}
+		bitPos += l
+		sizes[realBlocks-1] += size[sym]
+		valuePos += size[sym]
+	}
+	for _, n := range sizes {
+		b.sizes = binary.LittleEndian.AppendUint16(b.sizes, uint16(n-1))
+	}
+
+	// each index entry points at the value in the middle of its span
+	span := 1 << testIdxBits
+	for i := 0; i < (len(values)+span-1)/span; i++ {
+		mid := i*span + span/2
+		block := sort.Search(realBlocks, func(j int) bool { return starts[j] > mid }) - 1
+		b.index = binary.LittleEndian.AppendUint32(b.index, uint32(block))
+		b.index = binary.LittleEndian.AppendUint16(b.index, uint16(mid-starts[block]))
+	}
+
+	header = []byte{0, testBlockSize, testIdxBits, 0}
+	header = binary.LittleEndian.AppendUint32(header, uint32(realBlocks))
+	header = append(header, byte(maxLen), byte(minLen))
+	for _, n := range lowest {
+		header = binary.LittleEndian.AppendUint16(header, uint16(n))
+	}
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(pairs)))
+	for _, sym := range order {
+		left, right := pairs[sym][0], pairs[sym][1]
+		if right != 0xfff {
+			left, right = number[left], number[right]
+		}
+		header = append(header, byte(left), byte(left>>8&0x0f)|byte(right<<4), byte(right>>4))
+	}
+	if len(pairs)&1 != 0 {
+		header = append(header, 0)
+	}
+	return header, b, nil
+}
+
+// huffmanLengths gives every symbol with a weight its code length, ties merge the older node first
+func huffmanLengths(weights []int) []int {
+	type node struct {
+		weight, order int
+		symbols       []int
+	}
+	var nodes []node
+	for sym, w := range weights {
+		if w > 0 {
+			nodes = append(nodes, node{weight: w, order: sym, symbols: []int{sym}})
+		}
+	}
+	lengths := make([]int, len(weights))
+	for next := len(weights); len(nodes) > 1; next++ {
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].weight < nodes[j].weight || nodes[i].weight == nodes[j].weight && nodes[i].order < nodes[j].order
+		})
+		a, b := nodes[0], nodes[1]
+		symbols := append(append([]int(nil), a.symbols...), b.symbols...)
+		for _, sym := range symbols {
+			lengths[sym]++
+		}
+		nodes = append(nodes[2:], node{weight: a.weight + b.weight, order: next, symbols: symbols})
+	}
+	return lengths
+}
+
+func allEqual(values []int) bool {
+	for _, v := range values {
+		if v != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGenerateTables(t *testing.T) {
+	if !*update {
+		t.Skip("run with -update to regenerate testdata")
+	}
+	solved := make(map[int]*solution)
+	for _, piece := range []int{chess.Knight, chess.Bishop, chess.Rook, chess.Queen, chess.Pawn} {
+		s := solve(t, piece, solved)
+		solved[piece] = s
+		for _, dtz := range []bool{false, true} {
+			spec := tableSpec(piece, dtz)
+			ext := wdlSuffix
+			if dtz {
+				ext = dtzSuffix
+			}
+			path := filepath.Join("testdata", spec.name+ext)
+			values, maps := tableValues(piece, s, spec)
+			if err := writeTable(path, spec, values, maps); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// read everything back through the prober
+	tb := openTestdata(t)
+	for piece, s := range solved {
+		for idx := range s.wdl {
+			board, ok := solverBoard(piece, idx>>18 == 1, idx>>12&63, idx>>6&63, idx&63)
+			if !ok {
+				continue
+			}
+			want := ProbeResult{WDL: WDL(s.wdl[idx]), DTZ: int(s.dtz[idx])}
+			if !chess.HasLegalMoves(&board) {
+				want.DTZ = 0 // finished games
+			}
+			if got, err := tb.ProbeDTZ(&board); err != nil || got != want {
+				t.Fatalf("%s: got %+v, %v, want %+v", board.FEN(), got, err, want)
+			}
+		}
+	}
+}
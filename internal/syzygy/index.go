@@ -0,0 +1,317 @@
+package syzygy
+
+//https://github.com/syzygy1/tb/blob/master/src/tbcore.c
+//https://github.com/official-stockfish/Stockfish/blob/master/src/syzygy/tbprobe.cpp
+
+// Squares are mapped onto a canonical part of the board before indexing: the
+// leading piece goes to the a1-d1-d4 triangle (pawnless tables) or files a-d (pawn tables).
+
+// triangle numbers the a1-d1-d4 triangle, off-diagonal squares first (b1 = 0 .. d3 = 5, a1 = 6 .. d4 = 9)
+var triangle = [64]int{
+	6, 0, 1, 2, 2, 1, 0, 6,
+	0, 7, 3, 4, 4, 3, 7, 0,
+	1, 3, 8, 5, 5, 8, 3, 1,
+	2, 4, 5, 9, 9, 5, 4, 2,
+	2, 4, 5, 9, 9, 5, 4, 2,
+	1, 3, 8, 5, 5, 8, 3, 1,
+	0, 7, 3, 4, 4, 3, 7, 0,
+	6, 0, 1, 2, 2, 1, 0, 6,
+}
+
+// lower numbers the squares below the a1-h8 diagonal (b1 = 0 .. h7 = 27), the diagonal itself is 28..35
+var lower = [64]int{
+	28, 0, 1, 2, 3, 4, 5, 6,
+	0, 29, 7, 8, 9, 10, 11, 12,
+	1, 7, 30, 13, 14, 15, 16, 17,
+	2, 8, 13, 31, 18, 19, 20, 21,
+	3, 9, 14, 18, 32, 22, 23, 24,
+	4, 10, 15, 19, 22, 33, 25, 26,
+	5, 11, 16, 20, 23, 25, 34, 27,
+	6, 12, 17, 21, 24, 26, 27, 35,
+}
+
+// ptwist orders pawn squares for the leading pawns: edge files and low ranks come last
+var ptwist = [64]int{
+	0, 0, 0, 0, 0, 0, 0, 0,
+	47, 35, 23, 11, 10, 22, 34, 46,
+	45, 33, 21, 9, 8, 20, 32, 44,
+	43, 31, 19, 7, 6, 18, 30, 42,
+	41, 29, 17, 5, 4, 16, 28, 40,
+	39, 27, 15, 3, 2, 14, 26, 38,
+	37, 25, 13, 1, 0, 12, 24, 36,
+	0, 0, 0, 0, 0, 0, 0, 0,
+}
+
+// flap numbers the pawn squares of files a-d by file then rank (a2 = 0 .. d7 = 23), e-h mirror them
+var flap = [64]int{
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0, 6, 12, 18, 18, 12, 6, 0,
+	1, 7, 13, 19, 19, 13, 7, 1,
+	2, 8, 14, 20, 20, 14, 8, 2,
+	3, 9, 15, 21, 21, 15, 9, 3,
+	4, 10, 16, 22, 22, 16, 10, 4,
+	5, 11, 17, 23, 23, 17, 11, 5,
+	0, 0, 0, 0, 0, 0, 0, 0,
+}
+
+var invflap = [24]int{
+	8, 16, 24, 32, 40, 48,
+	9, 17, 25, 33, 41, 49,
+	10, 18, 26, 34, 42, 50,
+	11, 19, 27, 35, 43, 51,
+}
+
+var fileToFile = [8]int{0, 1, 2, 3, 3, 2, 1, 0}
+
+const (
+	uniquePiecesSize = 31332 // three unique leading pieces
+	twoKingsSize     = 462   // the two kings lead
+)
+
+var (
+	binomial [7][64]uint64 // [k][n] n choose k
+	kkIndex  [10][64]int   // [triangle of the first king][square of the second], -1 if illegal
+	pawnIdx  [6][24]uint64 // [leading pawns-1][flap of the first one]
+	pawnSize [6][4]uint64  // [leading pawns-1][file] positions of the leading pawns
+)
+
+func init() {
+	for k := range binomial {
+		for n := range binomial[k] {
+			binomial[k][n] = choose(n, k)
+		}
+	}
+
+	// kings on the diagonal come last, the first king is flipped below it otherwise
+	var onDiagonal [][2]int
+	code := 0
+	for idx := 0; idx < 10; idx++ {
+		for i := range kkIndex[idx] {
+			kkIndex[idx][i] = -1
+		}
+		s1 := triangleSquare(idx)
+		for s2 := 0; s2 < 64; s2++ {
+			switch {
+			case kingDistance(s1, s2) <= 1:
+			case offDiagonal(s1) == 0 && offDiagonal(s2) > 0:
+			case offDiagonal(s1) == 0 && offDiagonal(s2) == 0:
+				onDiagonal = append(onDiagonal, [2]int{idx, s2})
+			default:
+				kkIndex[idx][s2] = code
+				code++
+			}
+		}
+	}
+	for _, p := range onDiagonal {
+		kkIndex[p[0]][p[1]] = code
+		code++
+	}
+
+	for leading := 0; leading < len(pawnIdx); leading++ {
+		for file := 0; file < 4; file++ {
+			var sum uint64
+			for j := file * 6; j < file*6+6; j++ {
+				pawnIdx[leading][j] = sum
+				sum += binom(ptwist[invflap[j]], leading)
+			}
+			pawnSize[leading][file] = sum
+		}
+	}
+}
+
+func choose(n, k int) uint64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := uint64(1)
+	for i := 0; i < k; i++ {
+		result = result * uint64(n-i) / uint64(i+1)
+	}
+	return result
+}
+
+func binom(n, k int) uint64 {
+	if n < 0 || k >= len(binomial) {
+		return choose(n, k)
+	}
+	return binomial[k][n]
+}
+
+// triangleSquare is the square triangle maps to idx, inside a1-d1-d4
+func triangleSquare(idx int) int {
+	for sq := 0; sq < 32; sq++ {
+		if sq%8 < 4 && sq%8 >= sq/8 && triangle[sq] == idx {
+			return sq
+		}
+	}
+	return -1
+}
+
+func kingDistance(a, b int) int {
+	return max(abs(a%8-b%8), abs(a/8-b/8))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// offDiagonal is positive above the a1-h8 diagonal, negative below and 0 on it
+func offDiagonal(sq int) int {
+	return sq/8 - sq%8
+}
+
+func flipDiagonal(sq int) int {
+	return ((sq >> 3) | (sq << 3)) & 63
+}
+
+// encodePieces indexes a pawnless position, pos is reordered and mirrored in place
+func (e *encoding) encodePieces(pos []int) uint64 {
+	n := len(pos)
+	if pos[0]&0x04 != 0 {
+		for i := range pos {
+			pos[i] ^= 0x07
+		}
+	}
+	if pos[0]&0x20 != 0 {
+		for i := range pos {
+			pos[i] ^= 0x38
+		}
+	}
+	leading := 2
+	if e.uniquePieces {
+		leading = 3
+	}
+	for i := 0; i < leading; i++ {
+		if d := offDiagonal(pos[i]); d != 0 {
+			if d > 0 {
+				for j := range pos {
+					pos[j] = flipDiagonal(pos[j])
+				}
+			}
+			break
+		}
+	}
+
+	var idx uint64
+	if e.uniquePieces {
+		i := btoi(pos[1] > pos[0])
+		j := btoi(pos[2] > pos[0]) + btoi(pos[2] > pos[1])
+		switch {
+		case offDiagonal(pos[0]) != 0:
+			idx = uint64(triangle[pos[0]]*63*62 + (pos[1]-i)*62 + (pos[2] - j))
+		case offDiagonal(pos[1]) != 0:
+			idx = uint64(6*63*62 + diagonal(pos[0])*28*62 + lower[pos[1]]*62 + pos[2] - j)
+		case offDiagonal(pos[2]) != 0:
+			idx = uint64(6*63*62 + 4*28*62 + diagonal(pos[0])*7*28 + (diagonal(pos[1])-i)*28 + lower[pos[2]])
+		default:
+			idx = uint64(6*63*62 + 4*28*62 + 4*7*28 + diagonal(pos[0])*7*6 + (diagonal(pos[1])-i)*6 + (diagonal(pos[2]) - j))
+		}
+	} else {
+		idx = uint64(kkIndex[triangle[pos[0]]][pos[1]])
+	}
+	idx *= e.factor[0]
+
+	return idx + e.encodeGroups(pos, leading, n)
+}
+
+// encodePawns indexes a position with pawns, pos starts with the leading pawns,
+// pos[0] being the one pawnFile picked
+func (e *encoding) encodePawns(pos []int, leadingPawns, otherPawns int) uint64 {
+	n := len(pos)
+	if pos[0]&0x04 != 0 {
+		for i := range pos {
+			pos[i] ^= 0x07
+		}
+	}
+
+	// the other leading pawns by descending ptwist
+	for i := 1; i < leadingPawns; i++ {
+		for j := i + 1; j < leadingPawns; j++ {
+			if ptwist[pos[i]] < ptwist[pos[j]] {
+				pos[i], pos[j] = pos[j], pos[i]
+			}
+		}
+	}
+	t := leadingPawns - 1
+	idx := pawnIdx[t][flap[pos[0]]]
+	for i := t; i > 0; i-- {
+		idx += binom(ptwist[pos[i]], t-i+1)
+	}
+	idx *= e.factor[0]
+
+	// the other side's pawns can only stand on the 48 squares of ranks 2-7
+	i := leadingPawns
+	if otherPawns > 0 {
+		t := i + otherPawns
+		sortSquares(pos[i:t])
+		var s uint64
+		for m := i; m < t; m++ {
+			s += binom(pos[m]-countBelow(pos[:i], pos[m])-8, m-i+1)
+		}
+		idx += s * e.factor[i]
+		i = t
+	}
+
+	return idx + e.encodeGroups(pos, i, n)
+}
+
+// encodeGroups adds the remaining groups of identical pieces from pos[i:], each in ascending square order
+func (e *encoding) encodeGroups(pos []int, i, n int) uint64 {
+	var idx uint64
+	for i < n {
+		t := e.norm[i]
+		sortSquares(pos[i : i+t])
+		var s uint64
+		for m := i; m < i+t; m++ {
+			s += binom(pos[m]-countBelow(pos[:i], pos[m]), m-i+1)
+		}
+		idx += s * e.factor[i]
+		i += t
+	}
+	return idx
+}
+
+// diagonal numbers the a1-h8 diagonal by rank
+func diagonal(sq int) int {
+	return sq / 8
+}
+
+// countBelow counts the squares in placed that are lower than sq, sq skips over them
+func countBelow(placed []int, sq int) int {
+	n := 0
+	for _, p := range placed {
+		if sq > p {
+			n++
+		}
+	}
+	return n
+}
+
+func sortSquares(squares []int) {
+	for i := 1; i < len(squares); i++ {
+		for j := i; j > 0 && squares[j] < squares[j-1]; j-- {
+			squares[j], squares[j-1] = squares[j-1], squares[j]
+		}
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// pawnFile moves the leading pawn (lowest flap, nearest the edge and then the 2nd rank) to pos[0]
+// and returns its file folded onto a-d
+func pawnFile(pos []int, leadingPawns int) int {
+	for i := 1; i < leadingPawns; i++ {
+		if flap[pos[0]] > flap[pos[i]] {
+			pos[0], pos[i] = pos[i], pos[0]
+		}
+	}
+	return fileToFile[pos[0]&0x07]
+}
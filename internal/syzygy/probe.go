@@ -0,0 +1,339 @@
+package syzygy
+
+import (
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+// wdlToDTZ is the dtz of a zeroing move by its wdl+2
+var wdlToDTZ = [5]int{-1, -101, 0, 101, 1}
+
+// probeWDL is the value of the position, en passant included
+func (tb *Tablebase) probeWDL(board *chess.Board) (int, error) {
+	v, _, err := tb.probeAB(board, -2, 2)
+	if err != nil || board.EnPassantSquare < 0 {
+		return v, err
+	}
+
+	v1, onlyEP, err := tb.probeEnPassant(board)
+	if err != nil {
+		return 0, err
+	}
+	if v1 > -3 {
+		if v1 >= v {
+			v = v1
+		} else if v == 0 && onlyEP {
+			// the en passant capture is forced even if it loses
+			v = v1
+		}
+	}
+	return v, nil
+}
+
+// probeEnPassant is the best wdl of the en passant captures, -3 without any,
+// the tables are built as if en passant wasn't possible
+func (tb *Tablebase) probeEnPassant(board *chess.Board) (best int, onlyEP bool, err error) {
+	best, onlyEP = -3, true
+	color := board.ActiveColor()
+	for _, m := range chess.GenerateLegalMoves(board) {
+		if m.To != board.EnPassantSquare || chess.GetPieceType(board, m.From, color) != chess.Pawn {
+			onlyEP = false
+			continue
+		}
+		undo := chess.MakeMove(board, m.From, m.To, m.Promotion)
+		v, _, err := tb.probeAB(board, -2, 2)
+		chess.UnmakeMove(board, undo)
+		if err != nil {
+			return 0, false, err
+		}
+		best = max(best, -v)
+	}
+	return best, onlyEP, nil
+}
+
+// probeAB searches the captures, the tables only hold positions without a winning capture,
+// success is 2 when a capture is the best move
+func (tb *Tablebase) probeAB(board *chess.Board, alpha, beta int) (v, success int, err error) {
+	enemy := board.Occupied[1-board.ActiveColor()]
+	for _, m := range chess.GenerateLegalMoves(board) {
+		if enemy&(chess.Bitboard(1)<<m.To) == 0 {
+			continue
+		}
+		undo := chess.MakeMove(board, m.From, m.To, m.Promotion)
+		v, _, err := tb.probeAB(board, -beta, -alpha)
+		chess.UnmakeMove(board, undo)
+		if err != nil {
+			return 0, 0, err
+		}
+		if v = -v; v > alpha {
+			if v >= beta {
+				return v, 2, nil
+			}
+			alpha = v
+		}
+	}
+
+	v, err = tb.probeWDLTable(board)
+	if err != nil {
+		return 0, 0, err
+	}
+	if alpha >= v {
+		return alpha, 1 + btoi(alpha > 0), nil
+	}
+	return v, 1, nil
+}
+
+func (tb *Tablebase) probeWDLTable(board *chess.Board) (int, error) {
+	if board.Occupied[chess.White]|board.Occupied[chess.Black] == board.Kings[chess.White]|board.Kings[chess.Black] {
+		return 0, nil
+	}
+	t, flip, err := tb.table(board, false)
+	if err != nil {
+		return 0, err
+	}
+	file, side, idx := t.index(board, flip)
+	return t.decompress(t.files[file][side].pairs, idx) - 2, nil
+}
+
+// probeDTZ is the signed distance to zeroing, en passant included
+func (tb *Tablebase) probeDTZ(board *chess.Board) (int, error) {
+	v, err := tb.probeDTZNoEP(board)
+	if err != nil || board.EnPassantSquare < 0 {
+		return v, err
+	}
+
+	v1, onlyEP, err := tb.probeEnPassant(board)
+	if err != nil {
+		return 0, err
+	}
+	if v1 == -3 {
+		return v, nil
+	}
+	v1 = wdlToDTZ[v1+2]
+	switch {
+	case v < -100:
+		if v1 >= 0 {
+			v = v1
+		}
+	case v < 0:
+		if v1 >= 0 || v1 < -100 {
+			v = v1
+		}
+	case v > 100:
+		if v1 > 0 {
+			v = v1
+		}
+	case v > 0:
+		if v1 == 1 {
+			v = v1
+		}
+	case v1 >= 0:
+		v = v1
+	case onlyEP:
+		v = v1
+	}
+	return v, nil
+}
+
+func (tb *Tablebase) probeDTZNoEP(board *chess.Board) (int, error) {
+	wdl, success, err := tb.probeAB(board, -2, 2)
+	if err != nil || wdl == 0 {
+		return 0, err
+	}
+	if success == 2 {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	color := board.ActiveColor()
+	enemy := board.Occupied[1-color]
+	moves := chess.GenerateLegalMoves(board)
+	if wdl > 0 {
+		// a pawn move that keeps the win zeroes right away
+		for _, m := range moves {
+			if chess.GetPieceType(board, m.From, color) != chess.Pawn || enemy&(chess.Bitboard(1)<<m.To) != 0 {
+				continue
+			}
+			undo := chess.MakeMove(board, m.From, m.To, m.Promotion)
+			v, err := tb.probeWDL(board)
+			chess.UnmakeMove(board, undo)
+			if err != nil {
+				return 0, err
+			}
+			if -v == wdl {
+				return dtzBeforeZeroing(wdl), nil
+			}
+		}
+	}
+
+	dtz, ok, err := tb.probeDTZTable(board, wdl)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		if wdl < 0 {
+			dtz = -dtz
+		}
+		return dtzBeforeZeroing(wdl) + dtz, nil
+	}
+
+	// the table holds the other side to move, search one ply
+	if wdl > 0 {
+		best := 0xffff
+		for _, m := range moves {
+			if chess.GetPieceType(board, m.From, color) == chess.Pawn || enemy&(chess.Bitboard(1)<<m.To) != 0 {
+				continue
+			}
+			undo := chess.MakeMove(board, m.From, m.To, m.Promotion)
+			v, err := tb.probeDTZ(board)
+			mated := v == -1 && !chess.HasLegalMoves(board)
+			chess.UnmakeMove(board, undo)
+			if err != nil {
+				return 0, err
+			}
+			if v = -v; mated {
+				best = 1
+			} else if v > 0 && v+1 < best {
+				best = v + 1
+			}
+		}
+		return best, nil
+	}
+
+	best := -1
+	for _, m := range moves {
+		undo := chess.MakeMove(board, m.From, m.To, m.Promotion)
+		var v int
+		if board.HalfmoveClock == 0 {
+			if wdl == -2 {
+				v = -1
+			} else {
+				v, _, err = tb.probeAB(board, 1, 2)
+				if v == 2 {
+					v = 0
+				} else {
+					v = -101
+				}
+			}
+		} else {
+			v, err = tb.probeDTZ(board)
+			v = -v - 1
+		}
+		chess.UnmakeMove(board, undo)
+		if err != nil {
+			return 0, err
+		}
+		best = min(best, v)
+	}
+	return best, nil
+}
+
+// probeDTZTable reads the dtz table, ok is false when it only stores the other side to move
+func (tb *Tablebase) probeDTZTable(board *chess.Board, wdl int) (dtz int, ok bool, err error) {
+	t, flip, err := tb.table(board, true)
+	if err != nil {
+		return 0, false, err
+	}
+	file, side, idx := t.index(board, flip)
+	flags := t.flags[file]
+	if !t.symmetric && int(flags&dtzFlagSide) != side {
+		return 0, false, nil
+	}
+
+	res := t.decompress(t.files[file][0].pairs, idx)
+	if flags&dtzFlagMapped != 0 {
+		m := t.mapIdx[file][wdlToMap[wdl+2]] + res
+		if flags&dtzFlagWideMap != 0 {
+			res = t.uint16(t.dtzMap + 2*m)
+		} else {
+			res = int(t.data[t.dtzMap+m])
+		}
+	}
+	if flags&plyFlags[wdl+2] == 0 || wdl&1 != 0 {
+		res *= 2
+	}
+	return res, true, nil
+}
+
+// dtzBeforeZeroing is the dtz of a position whose best move zeroes the counter
+func dtzBeforeZeroing(wdl int) int {
+	switch wdl {
+	case 2:
+		return 1
+	case 1:
+		return 101
+	case -1:
+		return -101
+	case -2:
+		return -1
+	}
+	return 0
+}
+
+// index finds the file, side and index of the position in t, flip says the
+// board's white pieces are the table's black ones
+func (t *table) index(board *chess.Board, flip bool) (file, side int, idx uint64) {
+	blackToMove := board.ActiveColor() == chess.Black
+	if t.symmetric {
+		flip, side = blackToMove, 0
+	} else {
+		side = btoi(blackToMove != flip)
+	}
+	colorFlip, mirror := 0, 0
+	if flip {
+		colorFlip, mirror = blackPiece, 0x38
+	}
+	encodingSide := side
+	if t.dtz {
+		encodingSide = 0
+	}
+
+	pos := make([]int, 0, t.num)
+	if !t.hasPawns {
+		e := &t.files[0][encodingSide]
+		pos = appendPieces(pos, board, e.pieces, colorFlip, mirror, t.num)
+		return 0, side, e.encodePieces(pos)
+	}
+
+	pos = appendSquares(pos, board, t.files[0][0].pieces[0]^colorFlip, mirror)
+	file = pawnFile(pos, t.pawns[0])
+	e := &t.files[file][encodingSide]
+	pos = appendPieces(pos, board, e.pieces, colorFlip, mirror, t.num)
+	return file, side, e.encodePawns(pos, t.pawns[0], t.pawns[1])
+}
+
+// appendPieces fills pos up to n squares, a group of equal codes is filled at once
+func appendPieces(pos []int, board *chess.Board, pieces []int, colorFlip, mirror, n int) []int {
+	for len(pos) < n {
+		before := len(pos)
+		pos = appendSquares(pos, board, pieces[before]^colorFlip, mirror)
+		if len(pos) == before {
+			break // the material doesn't match, can't happen for a table found by key
+		}
+	}
+	return pos
+}
+
+func appendSquares(pos []int, board *chess.Board, code, mirror int) []int {
+	color := int8(chess.White)
+	if code&blackPiece != 0 {
+		color = chess.Black
+	}
+	var bb chess.Bitboard
+	switch code&0x07 - 1 {
+	case chess.Pawn:
+		bb = board.Pawns[color]
+	case chess.Knight:
+		bb = board.Knights[color]
+	case chess.Bishop:
+		bb = board.Bishops[color]
+	case chess.Rook:
+		bb = board.Rooks[color]
+	case chess.Queen:
+		bb = board.Queens[color]
+	case chess.King:
+		bb = board.Kings[color]
+	}
+	for bb != 0 {
+		pos = append(pos, chess.PopLSB(&bb)^mirror)
+	}
+	return pos
+}
@@ -0,0 +1,247 @@
+package syzygy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+//https://github.com/syzygy1/tb
+//https://www.chessprogramming.org/Syzygy_Bases
+
+// WDL is the game theoretical value for the side to move, cursed wins and
+// blessed losses are decided by the fifty-move rule
+type WDL int8
+
+const (
+	Loss        WDL = -2
+	BlessedLoss WDL = -1
+	Draw        WDL = 0
+	CursedWin   WDL = 1
+	Win         WDL = 2
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "loss"
+	case BlessedLoss:
+		return "blessed loss"
+	case Draw:
+		return "draw"
+	case CursedWin:
+		return "cursed win"
+	case Win:
+		return "win"
+	}
+	return "unknown"
+}
+
+type ProbeResult struct {
+	WDL WDL
+	// DTZ counts the plies to the next capture or pawn move with best play, negative
+	// when losing and past ±100 for cursed wins and blessed losses. 0 for draws and finished games
+	DTZ int
+}
+
+var (
+	ErrMissingTable = errors.New("syzygy: no table for this material")
+	ErrCastling     = errors.New("syzygy: positions with castling rights aren't in the tables")
+	ErrTooManyMen   = errors.New("syzygy: too many pieces on the board")
+	ErrBadTable     = errors.New("syzygy: not a syzygy table")
+	// ErrUnsupported is returned for tables with a piece encoding only other
+	// variants use
+	ErrUnsupported = errors.New("syzygy: table encoding not supported")
+)
+
+const (
+	wdlSuffix = ".rtbw"
+	dtzSuffix = ".rtbz"
+)
+
+var (
+	wdlMagic = []byte{0x71, 0xe8, 0x23, 0x5d}
+	dtzMagic = []byte{0xd7, 0x66, 0x0c, 0xa5}
+)
+
+// Tablebase knows which table files are on disk, keyed by material like "KRPvKR".
+// Tables are read into memory the first time a position needs them
+type Tablebase struct {
+	wdl       map[string]string
+	dtz       map[string]string
+	maxPieces int
+
+	mu     sync.Mutex
+	loaded map[string]*table // by path
+}
+
+// Open scans the directories for .rtbw/.rtbz files, subdirectories aren't searched
+func Open(dirs ...string) (*Tablebase, error) {
+	tb := &Tablebase{wdl: make(map[string]string), dtz: make(map[string]string), loaded: make(map[string]*table)}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if entry.IsDir() || (ext != wdlSuffix && ext != dtzSuffix) {
+				continue
+			}
+			material := strings.TrimSuffix(name, ext)
+			if !validMaterial(material) {
+				continue
+			}
+			if ext == wdlSuffix {
+				tb.wdl[material] = filepath.Join(dir, name)
+			} else {
+				tb.dtz[material] = filepath.Join(dir, name)
+			}
+			if n := len(material) - 1; n > tb.maxPieces {
+				tb.maxPieces = n
+			}
+		}
+	}
+	return tb, nil
+}
+
+// MaxPieces is the largest piece count, kings included, that has a table
+func (tb *Tablebase) MaxPieces() int {
+	return tb.maxPieces
+}
+
+func (tb *Tablebase) Tables() int {
+	return len(tb.wdl)
+}
+
+// ProbeWDL returns the value of the position for the side to move. Probing plays
+// captures on board and takes them back, it's unchanged once this returns
+func (tb *Tablebase) ProbeWDL(board *chess.Board) (WDL, error) {
+	if result, done, err := tb.precheck(board); done || err != nil {
+		return result.WDL, err
+	}
+	v, err := tb.probeWDL(board)
+	return WDL(v), err
+}
+
+// ProbeDTZ returns the value and the distance to zeroing the fifty-move counter,
+// it needs both the wdl and the dtz table of the material
+func (tb *Tablebase) ProbeDTZ(board *chess.Board) (ProbeResult, error) {
+	if result, done, err := tb.precheck(board); done || err != nil {
+		return result, err
+	}
+	if _, _, err := tb.table(board, true); err != nil {
+		return ProbeResult{}, err
+	}
+	wdl, err := tb.probeWDL(board)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	dtz, err := tb.probeDTZ(board)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	return ProbeResult{WDL: WDL(wdl), DTZ: dtz}, nil
+}
+
+// precheck settles positions that need no table, done reports that result is final
+func (tb *Tablebase) precheck(board *chess.Board) (result ProbeResult, done bool, err error) {
+	if board.Flags&(chess.WK|chess.WQ|chess.BK|chess.BQ) != 0 {
+		return ProbeResult{}, true, ErrCastling
+	}
+
+	// finished games and bare kings need no table
+	if !chess.HasLegalMoves(board) {
+		if board.InCheck() {
+			return ProbeResult{WDL: Loss}, true, nil
+		}
+		return ProbeResult{WDL: Draw}, true, nil
+	}
+	pieces := chess.CountBits(board.Occupied[chess.White] | board.Occupied[chess.Black])
+	if pieces == 2 {
+		return ProbeResult{WDL: Draw}, true, nil
+	}
+	if pieces > tb.maxPieces {
+		return ProbeResult{}, true, ErrTooManyMen
+	}
+	return ProbeResult{}, false, nil
+}
+
+// table loads the table for the board's material, flip reports that it's
+// stored with the board's black pieces as white
+func (tb *Tablebase) table(board *chess.Board, dtz bool) (t *table, flip bool, err error) {
+	files := tb.wdl
+	if dtz {
+		files = tb.dtz
+	}
+	material := MaterialKey(board, chess.White)
+	path, ok := files[material]
+	if !ok {
+		// tables only exist with the stronger side first
+		material = MaterialKey(board, chess.Black)
+		if path, ok = files[material]; !ok {
+			return nil, false, fmt.Errorf("%w: %s", ErrMissingTable, material)
+		}
+		flip = true
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if t = tb.loaded[path]; t != nil {
+		return t, flip, nil
+	}
+	magic := wdlMagic
+	if dtz {
+		magic = dtzMagic
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if !bytes.HasPrefix(data, magic) || len(data) < 6 {
+		return nil, false, fmt.Errorf("%w: %s", ErrBadTable, filepath.Base(path))
+	}
+	if t, err = newTable(material, data, dtz); err != nil {
+		return nil, false, err
+	}
+	tb.loaded[path] = t
+	return t, flip, nil
+}
+
+// MaterialKey names the material like syzygy files do, first's pieces before the "v"
+func MaterialKey(board *chess.Board, first int8) string {
+	var sb strings.Builder
+	for i, color := range [2]int8{first, 1 - first} {
+		if i == 1 {
+			sb.WriteByte('v')
+		}
+		pieces := [6]chess.Bitboard{
+			board.Kings[color], board.Queens[color], board.Rooks[color],
+			board.Bishops[color], board.Knights[color], board.Pawns[color],
+		}
+		for j, bb := range pieces {
+			sb.WriteString(strings.Repeat(string("KQRBNP"[j]), chess.CountBits(bb)))
+		}
+	}
+	return sb.String()
+}
+
+func validMaterial(material string) bool {
+	sides := strings.Split(material, "v")
+	if len(sides) != 2 {
+		return false
+	}
+	for _, side := range sides {
+		if !strings.HasPrefix(side, "K") || strings.Trim(side, "KQRBNP") != "" || strings.Count(side, "K") != 1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,312 @@
+package syzygy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+func mustParseFEN(t *testing.T, fen string) chess.Board {
+	t.Helper()
+	board, err := chess.ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q): %v", fen, err)
+	}
+	return board
+}
+
+// fakeTables writes files with valid headers, enough for discovery and lookup
+func fakeTables(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		magic := wdlMagic
+		if filepath.Ext(name) == dtzSuffix {
+			magic = dtzMagic
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), append(magic, make([]byte, 60)...), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMaterialKey(t *testing.T) {
+	tests := []struct {
+		fen   string
+		first int8
+		want  string
+	}{
+		{"8/8/8/8/8/8/8/K6k w - - 0 1", chess.White, "KvK"},
+		{"8/8/8/8/8/8/1P6/KR5k w - - 0 1", chess.White, "KRPvK"},
+		{"8/8/8/8/8/8/1P6/KR5k w - - 0 1", chess.Black, "KvKRP"},
+		{"8/8/8/2n5/8/8/1P6/KQB3rk b - - 0 1", chess.White, "KQBPvKRN"},
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		if got := MaterialKey(&board, tt.first); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.fen, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := fakeTables(t, "KQvK.rtbw", "KQvK.rtbz", "KRPvKR.rtbw", "notes.txt", "KQ.rtbw", "KvKvK.rtbw")
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.Tables() != 2 || tb.MaxPieces() != 5 {
+		t.Errorf("got %d tables up to %d pieces, want 2 up to 5", tb.Tables(), tb.MaxPieces())
+	}
+	if _, err := Open(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestProbeWithoutTables(t *testing.T) {
+	tb, err := Open(fakeTables(t, "KQvK.rtbw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fen  string
+		want WDL
+	}{
+		{"8/8/8/8/8/8/8/K6k w - - 0 1", Draw},
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", Loss},      // mated
+		{"k7/8/1QK5/8/8/8/8/8 b - - 0 1", Draw},       // stalemate
+		{"k7/8/1Q6/8/8/8/8/KRRRRRRR b - - 0 1", Draw}, // stalemate beats the piece limit
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		got, err := tb.ProbeWDL(&board)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %v, %v, want %v", tt.fen, got, err, tt.want)
+		}
+	}
+}
+
+func TestProbeErrors(t *testing.T) {
+	dir := fakeTables(t, "KQvK.rtbw", "KRvK.rtbw")
+	os.WriteFile(filepath.Join(dir, "KRvK.rtbw"), []byte("not a table"), 0o644)
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fen  string
+		want error
+	}{
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", ErrCastling},
+		{"4k3/8/8/8/8/8/8/3QK3 w - - 0 1", ErrBadTable}, // header without pieces
+		{"4k3/8/8/8/8/8/8/3qK3 w - - 0 1", ErrBadTable}, // stored as KQvK
+		{"4k3/8/8/8/8/8/8/3BK3 w - - 0 1", ErrMissingTable},
+		{"4k3/8/8/8/8/8/8/3RK3 w - - 0 1", ErrBadTable},
+		{"4k3/8/8/8/8/8/8/2RRK3 w - - 0 1", ErrTooManyMen},
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		if _, err := tb.ProbeWDL(&board); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.fen, err, tt.want)
+		}
+	}
+
+	// dtz tables are separate files
+	board := mustParseFEN(t, "4k3/8/8/8/8/8/8/3QK3 w - - 0 1")
+	if _, err := tb.ProbeDTZ(&board); !errors.Is(err, ErrMissingTable) {
+		t.Errorf("dtz: got %v, want ErrMissingTable", err)
+	}
+}
+
+func TestKingPairIndex(t *testing.T) {
+	seen := make(map[int]bool)
+	for idx := range kkIndex {
+		for _, code := range kkIndex[idx] {
+			if code >= 0 {
+				seen[code] = true
+			}
+		}
+	}
+	if len(seen) != twoKingsSize || !seen[0] || !seen[twoKingsSize-1] {
+		t.Errorf("got %d king pair codes, want 0..%d", len(seen), twoKingsSize-1)
+	}
+}
+
+func openTestdata(t *testing.T) *Tablebase {
+	t.Helper()
+	tb, err := Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+func TestIndexSymmetry(t *testing.T) {
+	tb := openTestdata(t)
+	for _, piece := range []int{chess.Queen, chess.Pawn} {
+		for wk := 0; wk < 64; wk++ {
+			for sq := 0; sq < 64; sq++ {
+				for bk := 0; bk < 64; bk++ {
+					board, ok := solverBoard(piece, false, wk, sq, bk)
+					if !ok {
+						continue
+					}
+					tab, flip, err := tb.table(&board, false)
+					if err != nil {
+						t.Fatal(err)
+					}
+					_, _, want := tab.index(&board, flip)
+
+					// mirrored files for pawns, all eight symmetries without them
+					images := [][3]int{{wk ^ 7, sq ^ 7, bk ^ 7}}
+					if piece != chess.Pawn {
+						images = append(images,
+							[3]int{wk ^ 56, sq ^ 56, bk ^ 56},
+							[3]int{flipDiagonal(wk), flipDiagonal(sq), flipDiagonal(bk)},
+							[3]int{flipDiagonal(wk ^ 63), flipDiagonal(sq ^ 63), flipDiagonal(bk ^ 63)})
+					}
+					for _, img := range images {
+						mirrored, _ := solverBoard(piece, false, img[0], img[1], img[2])
+						if _, _, got := tab.index(&mirrored, flip); got != want {
+							t.Fatalf("%s: index %d, mirrored %s: %d", board.FEN(), want, mirrored.FEN(), got)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestProbeTables(t *testing.T) {
+	tb := openTestdata(t)
+	tests := []struct {
+		fen string
+		wdl WDL
+		dtz int
+	}{
+		{"k7/8/1K6/8/8/8/8/6Q1 w - - 0 1", Win, 1},  // Qg8#
+		{"K7/8/1k6/8/8/8/8/6q1 b - - 0 1", Win, 1},  // colors swapped
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", Draw, 0}, // stalemate
+		{"8/8/8/8/3k4/3Q4/8/7K b - - 0 1", Draw, 0}, // Kxd3
+		{"8/8/8/8/3K4/3q4/8/7k w - - 0 1", Draw, 0},
+		{"7k/8/6K1/8/8/8/8/R7 w - - 0 1", Win, 1}, // Ra8#
+		{"8/8/8/8/8/8/8/KB5k w - - 0 1", Draw, 0},
+		{"8/8/8/8/8/8/8/KN5k b - - 0 1", Draw, 0},
+		{"8/8/8/8/8/8/4P3/4K2k w - - 0 1", Win, 1},   // the pawn outruns the king
+		{"4k2K/4p3/8/8/8/8/8/8 b - - 0 1", Win, 1},   // colors swapped
+		{"k2K4/3p4/8/8/8/8/8/8 b - - 0 1", Win, 1},   // d-file, mirrored onto the index's files
+		{"k7/8/K7/P7/8/8/8/8 w - - 0 1", Draw, 0},    // rook pawn against the corner
+		{"4k3/4P3/4K3/8/8/8/8/8 b - - 0 1", Draw, 0}, // stalemate
+		{"8/8/8/8/8/k7/p7/K7 w - - 0 1", Draw, 0},    // stalemate
+		{"k7/P7/K7/8/8/8/8/8 b - - 0 1", Draw, 0},    // rook pawn, stalemate
+		{"k7/8/1K6/8/8/8/8/7R b - - 0 1", Loss, -2},  // Kb8 Rh8#
+		{"k7/8/2K5/8/8/8/8/1Q6 b - - 0 1", Loss, -2}, // Ka7 Qb7#
+		{"8/8/8/8/8/8/1kR5/7K b - - 0 1", Draw, 0},   // Kxc2
+		{"8/8/8/8/8/8/1kQ5/7K b - - 0 1", Draw, 0},   // Kxc2
+		{"7k/8/8/8/8/8/P7/K7 w - - 0 1", Win, 1},     // a4, the king is outside the square
+	}
+	for _, tt := range tests {
+		board := mustParseFEN(t, tt.fen)
+		wdl, err := tb.ProbeWDL(&board)
+		if err != nil || wdl != tt.wdl {
+			t.Errorf("%s: wdl %v, %v, want %v", tt.fen, wdl, err, tt.wdl)
+			continue
+		}
+		result, err := tb.ProbeDTZ(&board)
+		if err != nil || result != (ProbeResult{WDL: tt.wdl, DTZ: tt.dtz}) {
+			t.Errorf("%s: got %+v, %v, want dtz %d", tt.fen, result, err, tt.dtz)
+		}
+		if original := mustParseFEN(t, tt.fen); board.FEN() != original.FEN() {
+			t.Errorf("%s: probing changed the board to %s", tt.fen, board.FEN())
+		}
+	}
+}
+
+// TestTablesCompressed makes sure the testdata exercises the whole decoder:
+// codes of several lengths and symbols standing for pairs of values
+func TestTablesCompressed(t *testing.T) {
+	tb := openTestdata(t)
+	for _, fen := range []string{
+		"8/8/8/8/8/8/8/KQ5k w - - 0 1",
+		"8/8/8/8/8/8/8/KR5k w - - 0 1",
+		"8/8/8/8/8/8/P7/K6k w - - 0 1",
+	} {
+		board := mustParseFEN(t, fen)
+		for _, dtz := range []bool{false, true} {
+			tab, _, err := tb.table(&board, dtz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for f := range tab.files {
+				for side := range tab.files[f] {
+					d := tab.files[f][side].pairs
+					if d == nil || d.idxBits == 0 {
+						continue
+					}
+					pairs := 0
+					for _, n := range d.symLen {
+						pairs += btoi(n > 0)
+					}
+					if len(d.base) < 2 || pairs == 0 {
+						t.Errorf("%s dtz %v file %d side %d: %d code lengths, %d pair symbols", tab.name, dtz, f, side, len(d.base), pairs)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestLongestWins checks the known extremes: KQvK mates in at most 10 moves and KRvK in 16
+func TestLongestWins(t *testing.T) {
+	if testing.Short() {
+		t.Skip("probes every position")
+	}
+	tb := openTestdata(t)
+	for _, tt := range []struct {
+		piece    int
+		longest  int
+		material string
+	}{{chess.Queen, 19, "KQvK"}, {chess.Rook, 31, "KRvK"}} {
+		longest, slowestLoss := 0, 0
+		for _, blackToMove := range []bool{false, true} {
+			// the first king on the a1-d1-d4 triangle covers every position
+			for wk := 0; wk < 32; wk++ {
+				if wk%8 > 3 || wk%8 < wk/8 {
+					continue
+				}
+				for sq := 0; sq < 64; sq++ {
+					for bk := 0; bk < 64; bk++ {
+						board, ok := solverBoard(tt.piece, blackToMove, wk, sq, bk)
+						if !ok {
+							continue
+						}
+						result, err := tb.ProbeDTZ(&board)
+						if err != nil {
+							t.Fatalf("%s: %v", board.FEN(), err)
+						}
+						switch {
+						case !blackToMove && result.WDL != Win:
+							t.Fatalf("%s: got %v, white always wins", board.FEN(), result.WDL)
+						case !blackToMove && result.DTZ%2 == 0:
+							t.Fatalf("%s: dtz %d, white's wins take an odd number of plies", board.FEN(), result.DTZ)
+						case result.WDL == Win:
+							longest = max(longest, result.DTZ)
+						case result.WDL == Loss:
+							slowestLoss = min(slowestLoss, result.DTZ)
+						}
+					}
+				}
+			}
+		}
+		if longest != tt.longest || slowestLoss != -tt.longest-1 {
+			t.Errorf("%s: longest win %d plies, slowest loss %d, want %d and %d",
+				tt.material, longest, slowestLoss, tt.longest, -tt.longest-1)
+		}
+	}
+}
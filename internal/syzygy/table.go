@@ -0,0 +1,419 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// table headers code pieces as chess piece type+1, black pieces have bit 3 set
+const blackPiece = 8
+
+// flags of a dtz table, stored per file in its pairs header
+const (
+	dtzFlagSide     = 1  // the values are for black to move
+	dtzFlagMapped   = 2  // the values index a map per result
+	dtzFlagWinPlies = 4  // wins are stored in plies instead of moves
+	dtzFlagLossPly  = 8  // losses are stored in plies instead of moves
+	dtzFlagWideMap  = 16 // the maps hold uint16 values
+)
+
+const pairsSingleValue = 0x80
+
+// wdlToMap and plyFlags are indexed by wdl+2
+var (
+	wdlToMap = [5]int{1, 3, 0, 2, 0}
+	plyFlags = [5]byte{dtzFlagLossPly, 0, 0, 0, dtzFlagWinPlies}
+)
+
+// pairsData is one compressed sub-table: a sparse index into blocks of
+// canonical huffman codes, each code expanding to a tree of symbol pairs
+type pairsData struct {
+	flags      byte
+	idxBits    uint // 0 for tables with a single value
+	blockSize  uint
+	minLen     int // the value of single value tables
+	lowestSym  int // offset of the lowest symbol per code length, biased by minLen
+	symPat     int // offset of the 3 byte symbol pairs
+	symLen     []int
+	base       []uint64
+	numIndices int
+	numBlocks  int
+	realBlocks int
+	indexTable int
+	sizeTable  int
+	data       int
+}
+
+// encoding is how one side of one file turns squares into an index
+type encoding struct {
+	pieces       []int // header codes in index order
+	norm         []int // group sizes, set at the first piece of each group
+	factor       []uint64
+	size         uint64
+	uniquePieces bool // three unique pieces lead instead of the two kings
+	pairs        *pairsData
+}
+
+// table is a loaded .rtbw or .rtbz file
+type table struct {
+	name      string
+	data      []byte
+	dtz       bool
+	num       int
+	symmetric bool
+	hasPawns  bool
+	pawns     [2]int // leading color first
+
+	// [file][side], pawnless tables only use file 0 and dtz tables side 0
+	files [4][2]encoding
+
+	flags  [4]byte   // dtz only, per file
+	mapIdx [4][4]int // dtz only, [file][wdlToMap]
+	dtzMap int
+}
+
+func newTable(name string, data []byte, dtz bool) (*table, error) {
+	t := &table{name: name, data: data, dtz: dtz}
+	white, black, _ := strings.Cut(name, "v")
+	t.symmetric = white == black
+	t.num = len(white) + len(black)
+
+	t.pawns = [2]int{strings.Count(white, "P"), strings.Count(black, "P")}
+	t.hasPawns = t.pawns[0]+t.pawns[1] > 0
+	// the color with fewer pawns leads, white on ties
+	if t.pawns[1] > 0 && (t.pawns[0] == 0 || t.pawns[1] < t.pawns[0]) {
+		t.pawns[0], t.pawns[1] = t.pawns[1], t.pawns[0]
+	}
+
+	uniquePieces := false
+	if !t.hasPawns {
+		singles := 0
+		for _, side := range []string{white, black} {
+			for _, c := range "KQRBN" {
+				if strings.Count(side, string(c)) == 1 {
+					singles++
+				}
+			}
+		}
+		// two singles are the kings, fewer only happen in other variants
+		if singles < 2 {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+		}
+		uniquePieces = singles > 2
+	}
+
+	if err := t.setup(uniquePieces); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *table) setup(uniquePieces bool) (err error) {
+	defer func() {
+		// header reads of a truncated file run off the end
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s: truncated", ErrBadTable, t.name)
+		}
+	}()
+
+	if t.hasPawns != (t.data[4]&0x02 != 0) {
+		return fmt.Errorf("%w: %s: header doesn't match the material", ErrBadTable, t.name)
+	}
+	sides, files := 1, 1
+	if !t.dtz && t.data[4]&0x01 != 0 {
+		sides = 2
+	}
+	if t.hasPawns {
+		files = 4
+	}
+
+	// piece codes and their order, low nibble for side 0 and high nibble for side 1
+	p := 5
+	for f := 0; f < files; f++ {
+		skip := 1
+		if t.hasPawns && t.pawns[1] > 0 {
+			skip = 2
+		}
+		for s := 0; s < sides; s++ {
+			shift := 4 * s
+			order, order2 := int(t.data[p]>>shift)&0x0f, 0x0f
+			if skip == 2 {
+				order2 = int(t.data[p+1]>>shift) & 0x0f
+			}
+			pieces := make([]int, t.num)
+			for i := range pieces {
+				pieces[i] = int(t.data[p+skip+i]>>shift) & 0x0f
+			}
+			e := &t.files[f][s]
+			e.uniquePieces = uniquePieces
+			e.setup(t, pieces, order, order2, f)
+		}
+		p += t.num + skip
+	}
+	p += p & 1
+
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			e := &t.files[f][s]
+			e.pairs, p = t.setupPairs(p, e.size)
+		}
+		t.flags[f] = t.files[f][0].pairs.flags
+	}
+
+	if t.dtz {
+		t.dtzMap = p
+		for f := 0; f < files; f++ {
+			if t.flags[f]&dtzFlagMapped == 0 {
+				continue
+			}
+			if t.flags[f]&dtzFlagWideMap != 0 {
+				p += p & 1
+				for i := range t.mapIdx[f] {
+					t.mapIdx[f][i] = (p + 2 - t.dtzMap) / 2
+					p += 2 + 2*int(binary.LittleEndian.Uint16(t.data[p:]))
+				}
+			} else {
+				for i := range t.mapIdx[f] {
+					t.mapIdx[f][i] = p + 1 - t.dtzMap
+					p += 1 + int(t.data[p])
+				}
+			}
+		}
+		p += p & 1
+	}
+
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			pd := t.files[f][s].pairs
+			pd.indexTable = p
+			p += 6 * pd.numIndices
+		}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			pd := t.files[f][s].pairs
+			pd.sizeTable = p
+			p += 2 * pd.numBlocks
+		}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			pd := t.files[f][s].pairs
+			if pd.idxBits == 0 {
+				continue
+			}
+			p = (p + 0x3f) &^ 0x3f
+			pd.data = p
+			p += pd.realBlocks << pd.blockSize
+		}
+	}
+	if p > len(t.data) {
+		return fmt.Errorf("%w: %s: truncated", ErrBadTable, t.name)
+	}
+	return nil
+}
+
+// setup groups the pieces and works out each group's place value, order and
+// order2 say where the leading group and the other side's pawns go in the index
+func (e *encoding) setup(t *table, pieces []int, order, order2, file int) {
+	e.pieces = pieces
+	e.norm = make([]int, t.num)
+	e.factor = make([]uint64, t.num)
+
+	i := 0
+	switch {
+	case t.hasPawns:
+		e.norm[0] = t.pawns[0]
+		if t.pawns[1] > 0 {
+			e.norm[t.pawns[0]] = t.pawns[1]
+		}
+		i = t.pawns[0] + t.pawns[1]
+	case e.uniquePieces:
+		e.norm[0] = 3
+		i = 3
+	default:
+		e.norm[0] = 2
+		i = 2
+	}
+	for i < t.num {
+		for j := i; j < t.num && pieces[j] == pieces[i]; j++ {
+			e.norm[i]++
+		}
+		i += e.norm[i]
+	}
+
+	size := uint64(1)
+	i = e.norm[0]
+	if t.hasPawns && order2 < 0x0f {
+		i += e.norm[i]
+	}
+	n := 64 - i
+	for k := 0; i < t.num || k == order || k == order2; k++ {
+		switch {
+		case k == order:
+			e.factor[0] = size
+			switch {
+			case t.hasPawns:
+				size *= pawnSize[e.norm[0]-1][file]
+			case e.uniquePieces:
+				size *= uniquePiecesSize
+			default:
+				size *= twoKingsSize
+			}
+		case k == order2:
+			e.factor[e.norm[0]] = size
+			size *= binom(48-e.norm[0], e.norm[e.norm[0]])
+		default:
+			e.factor[i] = size
+			size *= binom(n, e.norm[i])
+			n -= e.norm[i]
+			i += e.norm[i]
+		}
+	}
+	e.size = size
+}
+
+// setupPairs reads the pairs header at p and returns where the next one starts
+func (t *table) setupPairs(p int, size uint64) (*pairsData, int) {
+	d := &pairsData{flags: t.data[p]}
+	if d.flags&pairsSingleValue != 0 {
+		if !t.dtz {
+			d.minLen = int(t.data[p+1])
+		}
+		return d, p + 2
+	}
+
+	d.blockSize = uint(t.data[p+1])
+	d.idxBits = uint(t.data[p+2])
+	d.realBlocks = int(binary.LittleEndian.Uint32(t.data[p+4:]))
+	d.numBlocks = d.realBlocks + int(t.data[p+3])
+	maxLen := int(t.data[p+8])
+	d.minLen = int(t.data[p+9])
+	h := maxLen - d.minLen + 1
+	numSyms := int(binary.LittleEndian.Uint16(t.data[p+10+2*h:]))
+	d.lowestSym = p + 10 - 2*d.minLen
+	d.symPat = p + 12 + 2*h
+	d.numIndices = int((size + 1<<d.idxBits - 1) >> d.idxBits)
+
+	d.symLen = make([]int, numSyms)
+	done := make([]bool, numSyms)
+	for s := range numSyms {
+		if !done[s] {
+			t.calcSymLen(d, s, done)
+		}
+	}
+
+	d.base = make([]uint64, h)
+	for i := h - 2; i >= 0; i-- {
+		lo := int64(t.uint16(p + 10 + 2*i))
+		hi := int64(t.uint16(p + 12 + 2*i))
+		d.base[i] = uint64((int64(d.base[i+1]) + lo - hi) / 2)
+	}
+	for i := range d.base {
+		d.base[i] <<= uint(64 - (d.minLen + i))
+	}
+	return d, p + 12 + 2*h + 3*numSyms + numSyms&1
+}
+
+// calcSymLen counts the values symbol s expands to, minus one
+func (t *table) calcSymLen(d *pairsData, s int, done []bool) {
+	left, right := t.symbolPair(d, s)
+	if right == 0x0fff {
+		d.symLen[s] = 0
+	} else {
+		if !done[left] {
+			t.calcSymLen(d, left, done)
+		}
+		if !done[right] {
+			t.calcSymLen(d, right, done)
+		}
+		d.symLen[s] = d.symLen[left] + d.symLen[right] + 1
+	}
+	done[s] = true
+}
+
+func (t *table) symbolPair(d *pairsData, s int) (left, right int) {
+	w := t.data[d.symPat+3*s:]
+	return int(w[1]&0x0f)<<8 | int(w[0]), int(w[2])<<4 | int(w[1]>>4)
+}
+
+// decompress returns the stored value of index idx
+func (t *table) decompress(d *pairsData, idx uint64) int {
+	if d.idxBits == 0 {
+		return d.minLen
+	}
+
+	mainIdx := idx >> d.idxBits
+	litIdx := int(idx&(1<<d.idxBits-1)) - 1<<(d.idxBits-1)
+	block := int(binary.LittleEndian.Uint32(t.data[d.indexTable+6*int(mainIdx):]))
+	litIdx += int(t.uint16(d.indexTable + 6*int(mainIdx) + 4))
+	for litIdx < 0 {
+		block--
+		litIdx += t.uint16(d.sizeTable+2*block) + 1
+	}
+	for litIdx > t.uint16(d.sizeTable+2*block) {
+		litIdx -= t.uint16(d.sizeTable+2*block) + 1
+		block++
+	}
+
+	ptr := d.data + block<<d.blockSize
+	code := t.uint64BE(ptr)
+	ptr += 8
+	bitCount := 0 // bits consumed from the low end of code
+	var sym int
+	for {
+		l := d.minLen
+		for code < d.base[l-d.minLen] {
+			l++
+		}
+		sym = t.uint16(d.lowestSym+2*l) + int((code-d.base[l-d.minLen])>>(64-l))
+		if litIdx < d.symLen[sym]+1 {
+			break
+		}
+		litIdx -= d.symLen[sym] + 1
+		code <<= l
+		bitCount += l
+		if bitCount >= 32 {
+			bitCount -= 32
+			code |= uint64(t.uint32BE(ptr)) << bitCount
+			ptr += 4
+		}
+	}
+
+	for d.symLen[sym] != 0 {
+		left, right := t.symbolPair(d, sym)
+		if litIdx < d.symLen[left]+1 {
+			sym = left
+		} else {
+			litIdx -= d.symLen[left] + 1
+			sym = right
+		}
+	}
+	left, _ := t.symbolPair(d, sym)
+	if t.dtz {
+		return left
+	}
+	return left & 0xff
+}
+
+func (t *table) uint16(p int) int {
+	return int(binary.LittleEndian.Uint16(t.data[p:]))
+}
+
+// uint64BE and uint32BE read zeros past the end, the last block's codes may end early
+func (t *table) uint64BE(p int) uint64 {
+	var buf [8]byte
+	if p < len(t.data) {
+		copy(buf[:], t.data[p:])
+	}
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+func (t *table) uint32BE(p int) uint32 {
+	var buf [4]byte
+	if p < len(t.data) {
+		copy(buf[:], t.data[p:])
+	}
+	return binary.BigEndian.Uint32(buf[:])
+}
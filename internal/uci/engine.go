@@ -0,0 +1,340 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//https://www.wbec-ridderkerk.nl/html/UCIProtocol.html
+
+const DefaultTimeout = 10 * time.Second
+
+var (
+	ErrTimeout = errors.New("uci: engine didn't answer in time")
+	ErrExited  = errors.New("uci: engine exited")
+)
+
+// Engine talks to an external UCI engine over its stdin/stdout.
+// Commands are serialized, one exchange at a time.
+type Engine struct {
+	Name    string
+	Author  string
+	Options map[string]string // announced options and their defaults
+	Timeout time.Duration     // how long to wait for replies that should be immediate
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string // engine output, closed when the engine exits
+	mu    sync.Mutex
+}
+
+type GoParams struct {
+	MoveTime time.Duration
+	Depth    int
+	Nodes    uint64
+	Infinite bool // search until the context is cancelled
+}
+
+type Result struct {
+	BestMove string
+	Ponder   string
+	Info     Info   // last info line that carried a principal variation
+	Infos    []Info // everything the engine reported, in order
+}
+
+// Start launches the engine binary and runs the uci handshake
+func Start(path string, args ...string) (*Engine, error) {
+	return StartCommand(exec.Command(path, args...))
+}
+
+// StartCommand is Start for a prepared command, e.g. with its own environment or working directory
+func StartCommand(cmd *exec.Cmd) (*Engine, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("uci: starting %s: %w", cmd.Path, err)
+	}
+
+	e := newEngine(stdin, stdout)
+	e.cmd = cmd
+	if err := e.handshake(); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func newEngine(stdin io.WriteCloser, stdout io.Reader) *Engine {
+	e := &Engine{
+		Options: make(map[string]string),
+		Timeout: DefaultTimeout,
+		stdin:   stdin,
+		lines:   make(chan string, 64),
+	}
+	go e.readLoop(stdout)
+	return e
+}
+
+func (e *Engine) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			e.lines <- line
+		}
+	}
+	close(e.lines)
+}
+
+func (e *Engine) send(command string) error {
+	_, err := io.WriteString(e.stdin, command+"\n")
+	return err
+}
+
+// readLine waits for the next output line, an empty deadline channel waits forever
+func (e *Engine) readLine(deadline <-chan time.Time) (string, error) {
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			return "", ErrExited
+		}
+		return line, nil
+	case <-deadline:
+		return "", ErrTimeout
+	}
+}
+
+// waitFor reads lines until one starts with token, handing the others to onLine
+func (e *Engine) waitFor(token string, onLine func(string)) (string, error) {
+	timer := time.NewTimer(e.Timeout)
+	defer timer.Stop()
+	for {
+		line, err := e.readLine(timer.C)
+		if err != nil {
+			return "", err
+		}
+		if firstWord(line) == token {
+			return line, nil
+		}
+		if onLine != nil {
+			onLine(line)
+		}
+	}
+}
+
+func (e *Engine) handshake() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.send("uci"); err != nil {
+		return err
+	}
+	_, err := e.waitFor("uciok", func(line string) {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 2 && fields[0] == "id" && fields[1] == "name":
+			e.Name = strings.Join(fields[2:], " ")
+		case len(fields) > 2 && fields[0] == "id" && fields[1] == "author":
+			e.Author = strings.Join(fields[2:], " ")
+		case fields[0] == "option":
+			name, def := parseOption(fields[1:])
+			if name != "" {
+				e.Options[name] = def
+			}
+		}
+	})
+	return err
+}
+
+// parseOption reads "name <name> type <type> default <value> ...", names and values may contain spaces
+func parseOption(fields []string) (name, def string) {
+	section := ""
+	var nameParts, defParts []string
+	for _, f := range fields {
+		switch f {
+		case "name", "type", "default", "min", "max", "var":
+			section = f
+			continue
+		}
+		switch section {
+		case "name":
+			nameParts = append(nameParts, f)
+		case "default":
+			defParts = append(defParts, f)
+		}
+	}
+	return strings.Join(nameParts, " "), strings.Join(defParts, " ")
+}
+
+func (e *Engine) IsReady() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isReady()
+}
+
+func (e *Engine) isReady() error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	_, err := e.waitFor("readyok", nil)
+	return err
+}
+
+func (e *Engine) SetOption(name, value string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.send("setoption name " + name + " value " + value); err != nil {
+		return err
+	}
+	return e.isReady()
+}
+
+// NewGame tells the engine the next position is from a different game
+func (e *Engine) NewGame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+	return e.isReady()
+}
+
+// SetPosition sets up fen (empty for the standard start) followed by moves in UCI notation
+func (e *Engine) SetPosition(fen string, moves []string) error {
+	command := "position startpos"
+	if fen != "" {
+		command = "position fen " + fen
+	}
+	if len(moves) > 0 {
+		command += " moves " + strings.Join(moves, " ")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.send(command)
+}
+
+// Go searches the current position until the engine answers with bestmove.
+// Cancelling ctx sends stop, the engine still gets Timeout to report its move.
+// Without a deadline on ctx only infinite searches may run for longer than MoveTime plus Timeout.
+func (e *Engine) Go(ctx context.Context, params GoParams) (Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := ctx.Deadline(); !ok && !params.Infinite {
+		// depth and node limits may take the engine forever
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.MoveTime+e.Timeout)
+		defer cancel()
+	}
+
+	command := "go"
+	if params.MoveTime > 0 {
+		command += " movetime " + strconv.FormatInt(params.MoveTime.Milliseconds(), 10)
+	}
+	if params.Depth > 0 {
+		command += " depth " + strconv.Itoa(params.Depth)
+	}
+	if params.Nodes > 0 {
+		command += " nodes " + strconv.FormatUint(params.Nodes, 10)
+	}
+	if params.Infinite {
+		command += " infinite"
+	}
+
+	var result Result
+	if err := e.send(command); err != nil {
+		return result, err
+	}
+
+	done := ctx.Done()
+	var deadline <-chan time.Time
+	for {
+		select {
+		case <-done:
+			// ask for the move once, then give the engine the usual grace period
+			if err := e.send("stop"); err != nil {
+				return result, err
+			}
+			done = nil
+			timer := time.NewTimer(e.Timeout)
+			defer timer.Stop()
+			deadline = timer.C
+
+		case <-deadline:
+			return result, ErrTimeout
+
+		case line, ok := <-e.lines:
+			if !ok {
+				return result, ErrExited
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "info":
+				info := ParseInfo(line)
+				result.Infos = append(result.Infos, info)
+				if len(info.PV) > 0 && info.MultiPV <= 1 {
+					result.Info = info
+				}
+			case "bestmove":
+				if len(fields) > 1 {
+					result.BestMove = fields[1]
+				}
+				if len(fields) > 3 && fields[2] == "ponder" {
+					result.Ponder = fields[3]
+				}
+				return result, nil
+			}
+		}
+	}
+}
+
+// Close sends quit and waits for the engine to exit, killing it if it doesn't.
+// An engine in the middle of an exchange is killed right away, Go then fails with ErrExited.
+func (e *Engine) Close() error {
+	if !e.mu.TryLock() {
+		e.stdin.Close()
+		if e.cmd == nil {
+			return nil
+		}
+		_ = e.cmd.Process.Kill()
+		return e.cmd.Wait()
+	}
+	defer e.mu.Unlock()
+
+	_ = e.send("quit")
+	e.stdin.Close()
+	if e.cmd == nil {
+		return nil
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- e.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(e.Timeout):
+		_ = e.cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+func firstWord(line string) string {
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
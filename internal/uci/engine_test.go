@@ -0,0 +1,289 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+// the test binary doubles as a fake engine, see fakeEngine
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("UCI_FAKE_ENGINE"); mode != "" {
+		fakeEngine(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeEngine answers with the first legal move. mode "silent" never answers go, "crash" exits on go.
+func fakeEngine(mode string) {
+	out := bufio.NewWriter(os.Stdout)
+	say := func(format string, args ...any) {
+		fmt.Fprintf(out, format+"\n", args...)
+		out.Flush()
+	}
+
+	board, _ := chess.ParseFEN(chess.StartingFEN)
+	bestMove := func() string {
+		moves := chess.GenerateLegalMoves(&board)
+		if len(moves) == 0 {
+			return "(none)"
+		}
+		return chess.MoveToUCI(moves[0])
+	}
+
+	var searching bool
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			say("id name Fake Engine 1.0")
+			say("id author Nobody")
+			say("option name Hash type spin default 16 min 1 max 1024")
+			say("option name Skill Level type spin default 20 min 0 max 20")
+			say("option name UCI_Chess960 type check default false")
+			say("uciok")
+		case "isready":
+			say("readyok")
+		case "position":
+			board = fakePosition(fields[1:])
+		case "go":
+			switch mode {
+			case "silent":
+				continue
+			case "crash":
+				return
+			}
+			say("info depth 1 seldepth 2 multipv 1 score cp 13 nodes 20 nps 2000 time 10 pv %s", bestMove())
+			if strings.Contains(strings.Join(fields, " "), "infinite") {
+				searching = true
+				continue
+			}
+			say("bestmove %s", bestMove())
+		case "stop":
+			if searching {
+				searching = false
+				say("bestmove %s", bestMove())
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+func fakePosition(fields []string) chess.Board {
+	fen := chess.StartingFEN
+	i := 1
+	if fields[0] == "fen" {
+		for i < len(fields) && fields[i] != "moves" {
+			i++
+		}
+		fen = strings.Join(fields[1:i], " ")
+	}
+	board, _ := chess.ParseFEN(fen)
+	if i < len(fields) && fields[i] == "moves" {
+		for _, s := range fields[i+1:] {
+			m, err := chess.ParseUCIMove(&board, s)
+			if err != nil {
+				break
+			}
+			chess.MakeMove(&board, m.From, m.To, m.Promotion)
+		}
+	}
+	return board
+}
+
+func startFake(t *testing.T, mode string) *Engine {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "UCI_FAKE_ENGINE="+mode)
+	e, err := StartCommand(cmd)
+	if err != nil {
+		t.Fatalf("starting fake engine: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestHandshake(t *testing.T) {
+	e := startFake(t, "normal")
+	if e.Name != "Fake Engine 1.0" || e.Author != "Nobody" {
+		t.Errorf("got name %q author %q", e.Name, e.Author)
+	}
+	if got := e.Options["Skill Level"]; got != "20" {
+		t.Errorf("Skill Level default = %q, want 20", got)
+	}
+	if err := e.IsReady(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetOption("Hash", "32"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGo(t *testing.T) {
+	e := startFake(t, "normal")
+	if err := e.SetPosition("", []string{"e2e4", "e7e5"}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := e.Go(context.Background(), GoParams{Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	board, _ := chess.ParseFEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2")
+	if _, err := chess.ParseUCIMove(&board, result.BestMove); err != nil {
+		t.Errorf("bestmove: %v", err)
+	}
+	if len(result.Infos) != 1 || result.Info.Depth != 1 || result.Info.Score.CP != 13 {
+		t.Errorf("unexpected info %+v", result.Infos)
+	}
+	if len(result.Info.PV) != 1 || result.Info.PV[0] != result.BestMove {
+		t.Errorf("pv %v doesn't start with %s", result.Info.PV, result.BestMove)
+	}
+}
+
+func TestGoInfiniteStopsOnCancel(t *testing.T) {
+	e := startFake(t, "normal")
+	e.SetPosition("", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := e.Go(ctx, GoParams{Infinite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove == "" {
+		t.Error("no bestmove after stop")
+	}
+}
+
+func TestGoTimeout(t *testing.T) {
+	e := startFake(t, "silent")
+	e.Timeout = 100 * time.Millisecond
+	e.SetPosition("", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := e.Go(ctx, GoParams{MoveTime: time.Second}); !errors.Is(err, ErrTimeout) {
+		t.Errorf("got %v, want ErrTimeout", err)
+	}
+}
+
+func TestGoWithoutDeadline(t *testing.T) {
+	e := startFake(t, "silent")
+	e.Timeout = 50 * time.Millisecond
+	e.SetPosition("", nil)
+
+	// stop after Timeout, then Timeout more for the bestmove
+	if _, err := e.Go(context.Background(), GoParams{Depth: 1}); !errors.Is(err, ErrTimeout) {
+		t.Errorf("got %v, want ErrTimeout", err)
+	}
+}
+
+func TestCloseDuringSearch(t *testing.T) {
+	e := startFake(t, "silent")
+	e.SetPosition("", nil)
+
+	searched := make(chan error, 1)
+	go func() {
+		_, err := e.Go(context.Background(), GoParams{Depth: 1})
+		searched <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		e.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close waited for the search")
+	}
+	if err := <-searched; !errors.Is(err, ErrExited) {
+		t.Errorf("got %v, want ErrExited", err)
+	}
+}
+
+func TestGoEngineExits(t *testing.T) {
+	e := startFake(t, "crash")
+	e.SetPosition("", nil)
+	if _, err := e.Go(context.Background(), GoParams{Depth: 1}); !errors.Is(err, ErrExited) {
+		t.Errorf("got %v, want ErrExited", err)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	p := NewPlayer(startFake(t, "normal"), GoParams{Depth: 1})
+
+	start, _ := chess.ParseFEN(chess.StartingFEN)
+	history := []chess.Board{start}
+	var moves []chess.Move
+	for i := 0; i < 6; i++ {
+		m, err := p.ChooseMove(history, moves)
+		if err != nil {
+			t.Fatalf("move %d: %v", i, err)
+		}
+		board := history[len(history)-1]
+		chess.MakeMove(&board, m.From, m.To, m.Promotion)
+		history = append(history, board)
+		moves = append(moves, m)
+	}
+}
+
+func TestPlayerChess960(t *testing.T) {
+	p := NewPlayer(startFake(t, "normal"), GoParams{Depth: 1})
+	start, err := chess.NewChess960Position(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ChooseMove([]chess.Board{start}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !p.chess960 {
+		t.Error("UCI_Chess960 wasn't set for a Chess960 start")
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	info := ParseInfo("info depth 12 seldepth 18 multipv 2 score mate -3 upperbound nodes 123456 nps 987654 time 125 pv e2e4 e7e5 g1f3")
+	if info.Depth != 12 || info.SelDepth != 18 || info.MultiPV != 2 {
+		t.Errorf("depths: %+v", info)
+	}
+	if info.Score.Mate != -3 || info.Score.CP != 0 || !info.Score.UpperBound || info.Score.LowerBound {
+		t.Errorf("score: %+v", info.Score)
+	}
+	if info.Nodes != 123456 || info.NPS != 987654 || info.Time != 125*time.Millisecond {
+		t.Errorf("counters: %+v", info)
+	}
+	if strings.Join(info.PV, " ") != "e2e4 e7e5 g1f3" {
+		t.Errorf("pv: %v", info.PV)
+	}
+
+	info = ParseInfo("info string NNUE evaluation using nn-abc.nnue enabled")
+	if info.String != "NNUE evaluation using nn-abc.nnue enabled" {
+		t.Errorf("string: %q", info.String)
+	}
+
+	info = ParseInfo("info depth 3 currmove e2e4 currmovenumber 1 score cp -25 lowerbound")
+	if info.Depth != 3 || info.Score.CP != -25 || !info.Score.LowerBound {
+		t.Errorf("got %+v", info)
+	}
+}
@@ -0,0 +1,75 @@
+package uci
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Score is either centipawns or a mate distance, from the engine's side to move
+type Score struct {
+	CP         int
+	Mate       int // moves to mate, negative when getting mated, 0 if not a mate score
+	LowerBound bool
+	UpperBound bool
+}
+
+type Info struct {
+	Depth    int
+	SelDepth int
+	MultiPV  int
+	Score    Score
+	Nodes    uint64
+	NPS      uint64
+	Time     time.Duration
+	PV       []string
+	String   string // free text from "info string"
+}
+
+// ParseInfo reads an "info ..." line, unknown tokens are skipped
+func ParseInfo(line string) Info {
+	var info Info
+	fields := strings.Fields(line)
+	for i := 1; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+		switch fields[i] {
+		case "depth":
+			info.Depth, _ = strconv.Atoi(next())
+		case "seldepth":
+			info.SelDepth, _ = strconv.Atoi(next())
+		case "multipv":
+			info.MultiPV, _ = strconv.Atoi(next())
+		case "nodes":
+			info.Nodes, _ = strconv.ParseUint(next(), 10, 64)
+		case "nps":
+			info.NPS, _ = strconv.ParseUint(next(), 10, 64)
+		case "time":
+			ms, _ := strconv.ParseInt(next(), 10, 64)
+			info.Time = time.Duration(ms) * time.Millisecond
+		case "score":
+			switch next() {
+			case "cp":
+				info.Score.CP, _ = strconv.Atoi(next())
+			case "mate":
+				info.Score.Mate, _ = strconv.Atoi(next())
+			}
+		case "lowerbound":
+			info.Score.LowerBound = true
+		case "upperbound":
+			info.Score.UpperBound = true
+		case "pv":
+			info.PV = append([]string(nil), fields[i+1:]...)
+			return info
+		case "string":
+			info.String = strings.Join(fields[i+1:], " ")
+			return info
+		}
+	}
+	return info
+}
@@ -0,0 +1,70 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	chess "github.com/zefir/szaszki-go-backend/internal/chessengine"
+)
+
+// Player lets an engine play a game: it sends the whole game each turn and
+// checks the answer against our own move generator
+type Player struct {
+	Engine *Engine
+	Limits GoParams
+
+	chess960 bool // UCI_Chess960 already switched on
+}
+
+func NewPlayer(engine *Engine, limits GoParams) *Player {
+	return &Player{Engine: engine, Limits: limits}
+}
+
+// ChooseMove asks the engine for a move, history holds the game's positions (start first, current last)
+// and moves the moves played between them
+func (p *Player) ChooseMove(history []chess.Board, moves []chess.Move) (chess.Move, error) {
+	if len(history) == 0 {
+		return chess.Move{}, errors.New("uci: no position to search")
+	}
+	start := history[0]
+
+	fen := start.FEN()
+	if start.Flags&chess.Chess960 != 0 {
+		if !p.chess960 {
+			if err := p.Engine.SetOption("UCI_Chess960", "true"); err != nil {
+				return chess.Move{}, err
+			}
+			p.chess960 = true
+		}
+		fen = start.ShredderFEN()
+	} else if fen == chess.StartingFEN {
+		fen = ""
+	}
+
+	ucis := make([]string, len(moves))
+	for i, m := range moves {
+		ucis[i] = chess.MoveToUCI(m)
+	}
+	if err := p.Engine.SetPosition(fen, ucis); err != nil {
+		return chess.Move{}, err
+	}
+
+	// an engine that overruns its movetime, or takes longer than Timeout to reach its depth, gets told to stop
+	ctx, cancel := context.WithTimeout(context.Background(), p.Limits.MoveTime+p.Engine.Timeout)
+	defer cancel()
+	result, err := p.Engine.Go(ctx, p.Limits)
+	if err != nil {
+		return chess.Move{}, err
+	}
+	current := history[len(history)-1]
+	m, err := chess.ParseUCIMove(&current, result.BestMove)
+	if err != nil {
+		return chess.Move{}, fmt.Errorf("uci: %s answered %q: %w", p.Engine.Name, result.BestMove, err)
+	}
+	return m, nil
+}
+
+func (p *Player) Close() error {
+	return p.Engine.Close()
+}